@@ -1,21 +1,27 @@
 package main
 
 import (
+	"context"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"time"
 
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
-	"github.com/go-chi/jwtauth"
 	httpSwagger "github.com/swaggo/http-swagger"
 	"github.com/waanvieira/api-users/configs"
 	_ "github.com/waanvieira/api-users/docs"
 	"github.com/waanvieira/api-users/internal/entity"
+	"github.com/waanvieira/api-users/internal/infra/auth"
 	databaseUser "github.com/waanvieira/api-users/internal/infra/database"
+	databaseCategory "github.com/waanvieira/api-users/internal/infra/database/category"
 	databaseProduct "github.com/waanvieira/api-users/internal/infra/database/product"
+	databaseTag "github.com/waanvieira/api-users/internal/infra/database/tag"
 	"github.com/waanvieira/api-users/internal/infra/webserver/handlers"
-	"gorm.io/driver/sqlite"
-	"gorm.io/gorm"
+	"github.com/waanvieira/api-users/internal/infra/webserver/middlewares"
+	"github.com/waanvieira/api-users/pkg/cursor"
 )
 
 // @title           Go Expert API Example
@@ -37,15 +43,43 @@ func main() {
 	configs, err := configs.LoadConfig(".")
 
 	if err != nil {
-		panic("erro ao criar user")
+		// Mostra o motivo, por exemplo a chave do JWT que não foi encontrada
+		log.Fatalf("config: %v", err)
 	}
-	// // Indicando qual banco vamor usar, nesse caso o sqlite
-	db, err := gorm.Open(sqlite.Open("test.db"), &gorm.Config{})
+	// Indicando qual banco vamos usar, o driver vem do DB_DRIVER do .env (sqlite, mysql ou postgres)
+	db, err := databaseUser.Open(databaseUser.Config{
+		Driver:          configs.DBDriver,
+		Host:            configs.DBHost,
+		Port:            configs.DBPort,
+		User:            configs.DBUser,
+		Password:        configs.DBPassword,
+		Name:            configs.DBName,
+		SSLMode:         configs.DBSSLMode,
+		MaxOpenConns:    configs.DBMaxOpenConns,
+		MaxIdleConns:    configs.DBMaxIdleConns,
+		ConnMaxLifetime: time.Duration(configs.DBConnMaxLifetime) * time.Second,
+	})
 	if err != nil {
-		panic(err)
+		log.Fatalf("database: %v", err)
+	}
+	// Subcomando para gerenciar as migrações, ex: go run . migrate up | down 1 | status
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(db, os.Args[2:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	// Subcomando para alterar o perfil de um usuário, ex: go run . user role admin@dev.com admin
+	if len(os.Args) > 1 && os.Args[1] == "user" {
+		if err := runUser(databaseUser.NewUser(db), os.Args[2:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	// As tabelas são criadas pelas migrações versionadas em internal/infra/database/migrations
+	if err := migrateOnStart(db, configs.DBMigrateOnStart, log.Writer()); err != nil {
+		log.Fatalf("migrations: %v", err)
 	}
-	// Criando as nossas migracoes
-	db.AutoMigrate(&entity.Product{}, &entity.User{})
 
 	r := chi.NewRouter()
 	// Cria logs em cada requisição
@@ -56,30 +90,64 @@ func main() {
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.WithValue("jwt", configs.TokenAuth))
 	r.Use(middleware.WithValue("JwtExperesIn", configs.JwtExpiresIn))
+	r.Use(middleware.WithValue("JwtRefreshExpiresIn", configs.JwtRefreshExpiresIn))
 
 	// // Estamos iniciando a struct de "classe" indicando qual banco de dados vamos usar
 	productDB := databaseProduct.NewProduct(db)
 	// Passamos a nossa "classe" concreta da nossa classe de manipulação de dados para o nosso handler (controller)
 	// fazer as tratativas criando a entidade e salvando no banco
-	produductHandler := handlers.NewProductHandler(productDB)
+	categoryDB := databaseCategory.NewCategory(db)
+	produductHandler := handlers.NewProductHandler(productDB, categoryDB, handlers.ProductHandlerConfig{
+		RequireIfMatch: configs.ProductRequireIfMatch,
+		CacheControl:   configs.ProductCacheControl,
+		Cursors:        cursor.NewSigner([]byte(configs.PaginationCursorSecret)),
+		DefaultLimit:   configs.ProductPageDefaultLimit,
+		MaxLimit:       configs.ProductPageMaxLimit,
+	})
 
 	userDB := databaseUser.NewUser(db)
-	userHandler := handlers.NewUserHandler(userDB)
+	refreshTokenDB := databaseUser.NewRefreshToken(db)
+	revokedTokenDB := databaseUser.NewRevokedToken(db)
+	// O GET /users usa os mesmos limites de página da listagem de produtos
+	userHandler := handlers.NewUserHandler(userDB, refreshTokenDB, revokedTokenDB, handlers.UserHandlerConfig{
+		DefaultLimit: configs.ProductPageDefaultLimit,
+		MaxLimit:     configs.ProductPageMaxLimit,
+	})
+
+	// Limpa de tempos em tempos os tokens revogados que já expiraram, para quando o servidor desligar
+	purgeCtx, stopPurge := context.WithCancel(context.Background())
+	defer stopPurge()
+	go revokedTokenDB.StartPurge(purgeCtx, time.Duration(configs.JwtRevocationPurgeInterval)*time.Second)
+	// Esvazia a lixeira de produtos, com retenção 0 os produtos removidos ficam lá até um admin apagar
+	if configs.ProductTrashRetention > 0 {
+		go productDB.StartPurge(purgeCtx, time.Duration(configs.ProductTrashPurgeInterval)*time.Second, time.Duration(configs.ProductTrashRetention)*time.Second)
+	}
 
 	// Injetamos o nosso método "CreateProduct" quando bater na rota de products
 	r.Route("/products", func(r chi.Router) {
 		// Middleware pega o nosso token e injeta no nosso contexto para poder pegar em qualquer rota, assim como fizemos no jwt
-		r.Use(jwtauth.Verifier(configs.TokenAuth))
+		r.Use(auth.Verifier(configs.TokenAuth))
 		// Esse middleware que vai verificar se o nosso token é valido, dentro do tempo certo entre outras validações
 		// Nesse caso todas as rotas dentro de /products estão com o middleware do JWT, se quisermos rotas sem jwt colocar fora
 		// Como é com as rotas de usuários
-		r.Use(jwtauth.Authenticator)
-		r.Post("/", produductHandler.CreateProduct)
+		r.Use(middlewares.Authenticator)
+		// Depois de validar o token verificamos se ele não foi revogado no logout
+		r.Use(middlewares.RejectRevokedTokens(revokedTokenDB))
+		// Qualquer usuário autenticado consulta, apenas editor (ou admin) cria e altera e apenas admin remove
+		// Alterar também exige ser o dono do produto ou admin, isso é verificado no handler
+		r.With(middlewares.RequireRole(entity.RoleEditor)).Post("/", produductHandler.CreateProduct)
 		r.Get("/", produductHandler.GetAllProducts)
+		r.Get("/search", produductHandler.SearchProducts)
+		// Lixeira: o DELETE só move o produto para cá, ele pode ser restaurado ou apagado de vez por um admin
+		r.With(middlewares.RequireRole(entity.RoleEditor)).Get("/trash", produductHandler.GetTrash)
+		r.With(middlewares.RequireRole(entity.RoleAdmin)).Delete("/trash/{id}", produductHandler.PurgeProduct)
+		r.With(middlewares.RequireRole(entity.RoleEditor)).Post("/{id}/restore", produductHandler.RestoreProduct)
+		r.With(middlewares.RequireRole(entity.RoleEditor)).Put("/{id}/categories", produductHandler.SetProductCategories)
 		r.Get("/{id}", produductHandler.FindByID)
-		r.Put("/{id}", produductHandler.UpdateProduct)
+		r.With(middlewares.RequireRole(entity.RoleEditor)).Put("/{id}", produductHandler.UpdateProduct)
+		r.With(middlewares.RequireRole(entity.RoleEditor)).Patch("/{id}", produductHandler.PatchProduct)
 		// userID := chi.URLParam(r, "userID")
-		r.Delete("/{id}", produductHandler.DeleteProduct)
+		r.With(middlewares.RequireRole(entity.RoleAdmin)).Delete("/{id}", produductHandler.DeleteProduct)
 		// Subrouters:
 		// r.Route("/{id}", func(r chi.Router) {
 		// 	r.Use(ArticleCtx)
@@ -90,19 +158,76 @@ func main() {
 
 	})
 
+	// Categorias: qualquer usuário autenticado consulta, editor cria e altera e apenas admin apaga
+	categoryHandler := handlers.NewCategoryHandler(categoryDB)
+	r.Route("/categories", func(r chi.Router) {
+		r.Use(auth.Verifier(configs.TokenAuth))
+		r.Use(middlewares.Authenticator)
+		r.Use(middlewares.RejectRevokedTokens(revokedTokenDB))
+		r.Get("/", categoryHandler.GetCategories)
+		r.Get("/{id}", categoryHandler.GetCategory)
+		r.With(middlewares.RequireRole(entity.RoleEditor)).Post("/", categoryHandler.CreateCategory)
+		r.With(middlewares.RequireRole(entity.RoleEditor)).Put("/{id}", categoryHandler.UpdateCategory)
+		r.With(middlewares.RequireRole(entity.RoleAdmin)).Delete("/{id}", categoryHandler.DeleteCategory)
+	})
+
+	// Tags são criadas junto com os produtos, aqui apenas a consulta com a quantidade de uso
+	tagHandler := handlers.NewTagHandler(databaseTag.NewTag(db))
+	r.Route("/tags", func(r chi.Router) {
+		r.Use(auth.Verifier(configs.TokenAuth))
+		r.Use(middlewares.Authenticator)
+		r.Use(middlewares.RejectRevokedTokens(revokedTokenDB))
+		r.Get("/", tagHandler.GetTags)
+	})
+
 	r.Route("/users", func(r chi.Router) {
 		r.Post("/", userHandler.CreateUser)
 		// r.Get("/{email}", userHandler.FindByEmail)
 		r.Post("/generate_token", userHandler.GetJWT)
+		r.Post("/refresh", userHandler.RefreshToken)
+		// Rotas de usuário que precisam de um token válido
+		r.Group(func(r chi.Router) {
+			r.Use(auth.Verifier(configs.TokenAuth))
+			r.Use(middlewares.Authenticator)
+			r.Use(middlewares.RejectRevokedTokens(revokedTokenDB))
+			r.Post("/logout", userHandler.Logout)
+			r.Get("/me", userHandler.GetMe)
+			r.Put("/me", userHandler.UpdateMe)
+			r.Delete("/me", userHandler.DeleteMe)
+			r.With(middlewares.RequireRole(entity.RoleAdmin)).Get("/", userHandler.GetAllUsers)
+		})
 
 	})
 
-	r.Get("/docs/*", httpSwagger.Handler(httpSwagger.URL("http://localhost:8001/docs/doc.json")))
+	// URL relativa para a documentação funcionar em qualquer host e porta configurados
+	// Chaves públicas para os outros serviços validarem os nossos tokens
+	r.Get("/.well-known/jwks.json", configs.TokenAuth.JWKS)
+	r.Get("/docs/*", httpSwagger.Handler(httpSwagger.URL("/docs/doc.json")))
 	r.Get("/test", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("Teste"))
 	})
 
-	http.ListenAndServe(":8001", r)
+	// Usamos o http.Server em vez do http.ListenAndServe para poder configurar os timeouts e fazer o shutdown
+	server := &http.Server{
+		Addr:         net.JoinHostPort(configs.WebserverHost, configs.WebserverPort),
+		Handler:      r,
+		ReadTimeout:  time.Duration(configs.WebserverReadTimeout) * time.Second,
+		WriteTimeout: time.Duration(configs.WebserverWriteTimeout) * time.Second,
+		IdleTimeout:  time.Duration(configs.WebserverIdleTimeout) * time.Second,
+	}
+	shutdownTimeout := time.Duration(configs.WebserverShutdownTimeout) * time.Second
+	// Depois que as requisições terminaram fechamos as conexões com o banco
+	err = serve(server, shutdownTimeout, func() error {
+		stopPurge()
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.Close()
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
 }
 
 // exemplo de um middleware próprio, seria um exmeplo de middleware para validar por exemplo ACL, verificar permissionamento de um usuário dependendo
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/waanvieira/api-users/internal/infra/database/migrations"
+	"gorm.io/gorm"
+)
+
+const migrateUsage = `usage:
+  server migrate up          aplica todas as migrações pendentes
+  server migrate down [N]    desfaz as últimas N migrações (padrão 1)
+  server migrate status      lista as migrações e se já foram aplicadas`
+
+// runMigrate executa o subcomando "migrate", ex: go run . migrate up
+func runMigrate(db *gorm.DB, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		for _, m := range applied {
+			fmt.Fprintf(out, "applied  %04d_%s\n", m.Version, m.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Fprintln(out, "no pending migrations")
+		}
+		return err
+	case "down":
+		n := 1
+		if len(args) > 1 {
+			n, err = strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid number of migrations %q\n%s", args[1], migrateUsage)
+			}
+		}
+		reverted, err := migrator.Down(n)
+		for _, m := range reverted {
+			fmt.Fprintf(out, "reverted %04d_%s\n", m.Version, m.Name)
+		}
+		return err
+	case "status":
+		status, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range status {
+			appliedAt := "pending"
+			if s.Applied {
+				appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Migration.Version, s.Migration.Name, appliedAt)
+		}
+		// Ex: o índice FTS5 da busca quando o servidor foi compilado sem a tag sqlite_fts5
+		for _, m := range migrator.Unavailable {
+			fmt.Fprintf(w, "%04d\t%s\tskipped (requires %s)\n", m.Version, m.Name, m.Requires)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown migrate command %q\n%s", args[0], migrateUsage)
+	}
+}
+
+// migrateOnStart aplica as migrações no boot quando DB_MIGRATE_ON_START=true (útil no ambiente local)
+// Caso contrário apenas avisa no log que existem migrações pendentes
+func migrateOnStart(db *gorm.DB, enabled bool, out io.Writer) error {
+	if enabled {
+		return runMigrate(db, []string{"up"}, out)
+	}
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+	pending, err := migrator.Pending()
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		fmt.Fprintf(out, "warning: %d pending migration(s), run \"server migrate up\"\n", len(pending))
+	}
+	return nil
+}
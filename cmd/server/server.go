@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// serve sobe o servidor http e fica esperando um SIGINT (ctrl+c) ou SIGTERM (enviado no deploy)
+// Quando recebe o sinal para de aceitar conexões novas e espera as requisições em andamento terminarem
+// até o shutdownTimeout, depois executa os onShutdown (fechar o banco por exemplo)
+func serve(server *http.Server, shutdownTimeout time.Duration, onShutdown ...func() error) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Printf("http server listening on %s", server.Addr)
+		// ListenAndServe sempre retorna um erro, o ErrServerClosed é o esperado quando chamamos o Shutdown
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+		close(serverErr)
+	}()
+
+	var err error
+	select {
+	case err = <-serverErr:
+		// O servidor nem chegou a subir, porta em uso por exemplo
+	case <-ctx.Done():
+		log.Println("shutdown signal received, draining connections")
+		// Se chegar um segundo sinal o processo é finalizado na hora
+		stop()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err = server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("http server shutdown: %v", err)
+		}
+	}
+
+	for _, fn := range onShutdown {
+		if fnErr := fn(); fnErr != nil {
+			log.Printf("shutdown: %v", fnErr)
+			err = errors.Join(err, fnErr)
+		}
+	}
+	log.Println("server stopped")
+	return err
+}
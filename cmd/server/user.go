@@ -0,0 +1,41 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/waanvieira/api-users/internal/entity"
+	"github.com/waanvieira/api-users/internal/infra/database"
+)
+
+const userUsage = `usage:
+  server user role <email> <viewer|editor|admin>    altera o perfil do usuário`
+
+// userRoleStore é o que o subcomando precisa do repositório de usuários
+type userRoleStore interface {
+	FindByEmail(email string) (*entity.User, error)
+	SetRole(id string, role entity.Role) error
+}
+
+var _ userRoleStore = (*database.User)(nil)
+
+// runUser executa o subcomando "user", usado principalmente para criar o primeiro admin
+func runUser(users userRoleStore, args []string, out io.Writer) error {
+	if len(args) != 3 || args[0] != "role" {
+		return errors.New(userUsage)
+	}
+	role, err := entity.ParseRole(args[2])
+	if err != nil {
+		return fmt.Errorf("%w\n%s", err, userUsage)
+	}
+	user, err := users.FindByEmail(args[1])
+	if err != nil {
+		return fmt.Errorf("user %q not found: %w", args[1], err)
+	}
+	if err := users.SetRole(user.ID.String(), role); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "user %s is now %s\n", user.Email, role)
+	return nil
+}
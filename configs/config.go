@@ -1,8 +1,13 @@
 package configs
 
 import (
-	"github.com/go-chi/jwtauth"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"strings"
+
 	"github.com/spf13/viper"
+	"github.com/waanvieira/api-users/internal/infra/auth"
 )
 
 var cfg *conf
@@ -10,16 +15,56 @@ var cfg *conf
 type conf struct {
 	// mapstructure é um alias para resolver o problema de letra maiuscula e minuscula, deixando assim equivalente um ao outro no .env
 	// Nunca esquecer de colocar "" entre o nome das variáveis do .env se não colocar não irá funcionar
-	DBDriver      string `mapstructure:"DB_DRIVER"`
-	DBHost        string `mapstructure:"DB_HOST"`
-	DBPort        string `mapstructure:"DB_PORT"`
-	DBUser        string `mapstructure:"DB_USER"`
-	DBPassword    string `mapstructure:"DB_PASSWORD"`
-	DBName        string `mapstructure:"DB_NAME"`
+	DBDriver   string `mapstructure:"DB_DRIVER"`
+	DBHost     string `mapstructure:"DB_HOST"`
+	DBPort     string `mapstructure:"DB_PORT"`
+	DBUser     string `mapstructure:"DB_USER"`
+	DBPassword string `mapstructure:"DB_PASSWORD"`
+	DBName     string `mapstructure:"DB_NAME"`
+	DBSSLMode  string `mapstructure:"DB_SSLMODE"`
+	// Pool de conexões, o tempo de vida da conexão é em segundos
+	DBMaxOpenConns    int `mapstructure:"DB_MAX_OPEN_CONNS"`
+	DBMaxIdleConns    int `mapstructure:"DB_MAX_IDLE_CONNS"`
+	DBConnMaxLifetime int `mapstructure:"DB_CONN_MAX_LIFETIME"`
+	// Quando true aplica as migrações pendentes ao subir o servidor
+	DBMigrateOnStart bool `mapstructure:"DB_MIGRATE_ON_START"`
+
+	WebserverHost string `mapstructure:"WEB_SERVER_HOST"`
 	WebserverPort string `mapstructure:"WEB_SERVER_PORT"`
+	// Timeouts do servidor http em segundos, o shutdown é o tempo máximo para terminar as requisições em andamento
+	WebserverReadTimeout     int `mapstructure:"WEB_SERVER_READ_TIMEOUT"`
+	WebserverWriteTimeout    int `mapstructure:"WEB_SERVER_WRITE_TIMEOUT"`
+	WebserverIdleTimeout     int `mapstructure:"WEB_SERVER_IDLE_TIMEOUT"`
+	WebserverShutdownTimeout int `mapstructure:"WEB_SERVER_SHUTDOWN_TIMEOUT"`
+
+	// HS256 (padrão, usa o JWT_SECRET), RS256 ou ES256 (usam a chave privada em PEM)
+	JWTAlgorithm      string `mapstructure:"JWT_ALGORITHM"`
+	JWTPrivateKeyFile string `mapstructure:"JWT_PRIVATE_KEY_FILE"`
+	JWTKeyID          string `mapstructure:"JWT_KEY_ID"`
+	// Chaves públicas antigas aceitas durante a rotação, separadas por vírgula no formato kid=caminho.pem
+	JWTVerifyKeys string `mapstructure:"JWT_VERIFY_KEYS"`
 	JWTSecret     string `mapstructure:"JWT_SECRET"`
 	JwtExpiresIn  int    `mapstructure:"JWT_EXPIRESIN"`
-	TokenAuth     *jwtauth.JWTAuth
+	// Tempo de vida do refresh token em segundos
+	JwtRefreshExpiresIn int `mapstructure:"JWT_REFRESH_EXPIRESIN"`
+	// Intervalo em segundos para apagar os tokens revogados que já expiraram
+	JwtRevocationPurgeInterval int `mapstructure:"JWT_REVOCATION_PURGE_INTERVAL"`
+	TokenAuth                  *auth.JWTAuth
+
+	// Exige o If-Match (ETag do produto) no PUT, PATCH e DELETE de produtos
+	ProductRequireIfMatch bool `mapstructure:"PRODUCT_REQUIRE_IF_MATCH"`
+	// Cache-Control enviado no GET de produtos, ex.: "public, max-age=60" para a CDN
+	ProductCacheControl string `mapstructure:"PRODUCT_CACHE_CONTROL"`
+	// Tempo em segundos que um produto removido fica na lixeira antes de ser apagado de vez, 0 mantém para sempre
+	ProductTrashRetention int `mapstructure:"PRODUCT_TRASH_RETENTION"`
+	// Intervalo em segundos para apagar os produtos da lixeira que passaram da retenção
+	ProductTrashPurgeInterval int `mapstructure:"PRODUCT_TRASH_PURGE_INTERVAL"`
+	// Chave que assina os cursores da paginação, vazia usa uma chave derivada do JWT_SECRET
+	PaginationCursorSecret string `mapstructure:"PAGINATION_CURSOR_SECRET"`
+	// Tamanho da página de produtos quando o ?limit= não é informado
+	ProductPageDefaultLimit int `mapstructure:"PRODUCT_PAGE_DEFAULT_LIMIT"`
+	// Maior ?limit= aceito na listagem de produtos, acima disso a página é reduzida a esse tamanho
+	ProductPageMaxLimit int `mapstructure:"PRODUCT_PAGE_MAX_LIMIT"`
 }
 
 func LoadConfig(path string) (*conf, error) {
@@ -30,6 +75,33 @@ func LoadConfig(path string) (*conf, error) {
 	viper.SetConfigFile(".env")
 	// Adcionando essa linha as configurações sempre irão pegar do .env, não vai subscrever outras variáveis
 	viper.AutomaticEnv()
+	// Valores padrão para as variáveis que não são obrigatórias no .env
+	viper.SetDefault("DB_DRIVER", "sqlite")
+	viper.SetDefault("DB_NAME", "test.db")
+	viper.SetDefault("DB_SSLMODE", "disable")
+	viper.SetDefault("DB_MAX_OPEN_CONNS", 25)
+	viper.SetDefault("DB_MAX_IDLE_CONNS", 25)
+	viper.SetDefault("DB_CONN_MAX_LIFETIME", 300)
+	viper.SetDefault("DB_MIGRATE_ON_START", false)
+	viper.SetDefault("WEB_SERVER_HOST", "")
+	viper.SetDefault("WEB_SERVER_PORT", "8001")
+	viper.SetDefault("WEB_SERVER_READ_TIMEOUT", 10)
+	viper.SetDefault("WEB_SERVER_WRITE_TIMEOUT", 30)
+	viper.SetDefault("WEB_SERVER_IDLE_TIMEOUT", 120)
+	viper.SetDefault("WEB_SERVER_SHUTDOWN_TIMEOUT", 20)
+	viper.SetDefault("JWT_ALGORITHM", "HS256")
+	viper.SetDefault("JWT_PRIVATE_KEY_FILE", "")
+	viper.SetDefault("JWT_KEY_ID", "")
+	viper.SetDefault("JWT_VERIFY_KEYS", "")
+	viper.SetDefault("JWT_REFRESH_EXPIRESIN", 604800)
+	viper.SetDefault("JWT_REVOCATION_PURGE_INTERVAL", 600)
+	viper.SetDefault("PRODUCT_REQUIRE_IF_MATCH", true)
+	viper.SetDefault("PRODUCT_CACHE_CONTROL", "private, no-cache")
+	viper.SetDefault("PRODUCT_TRASH_RETENTION", 2592000)
+	viper.SetDefault("PRODUCT_TRASH_PURGE_INTERVAL", 3600)
+	viper.SetDefault("PAGINATION_CURSOR_SECRET", "")
+	viper.SetDefault("PRODUCT_PAGE_DEFAULT_LIMIT", 20)
+	viper.SetDefault("PRODUCT_PAGE_MAX_LIMIT", 100)
 	err := viper.ReadInConfig()
 	if err != nil {
 		return nil, err
@@ -38,8 +110,37 @@ func LoadConfig(path string) (*conf, error) {
 	if err != nil {
 		panic(err)
 	}
-	cfg.TokenAuth = jwtauth.New("HS256", []byte(cfg.JWTSecret), nil)
-	return cfg, err
+	// Monta quem assina e valida os tokens, com chave assimétrica os outros serviços validam pelo /.well-known/jwks.json
+	cfg.TokenAuth, err = auth.New(auth.Config{
+		Algorithm:      cfg.JWTAlgorithm,
+		Secret:         cfg.JWTSecret,
+		PrivateKeyFile: cfg.JWTPrivateKeyFile,
+		KeyID:          cfg.JWTKeyID,
+		VerifyKeyFiles: strings.Split(cfg.JWTVerifyKeys, ","),
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Sem segredo próprio derivamos uma chave do JWT_SECRET, assim a chave que assina os tokens não assina os cursores
+	if cfg.PaginationCursorSecret == "" && cfg.JWTSecret != "" {
+		cfg.PaginationCursorSecret = deriveKey(cfg.JWTSecret, "pagination-cursor")
+	}
+	// Sem nenhum segredo (ex: JWT com chave assimétrica) a chave é gerada na subida e os cursores valem até reiniciar
+	if cfg.PaginationCursorSecret == "" {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		cfg.PaginationCursorSecret = string(key)
+	}
+	return cfg, nil
+}
+
+// deriveKey gera uma chave para outro uso a partir do segredo: HMAC-SHA256(segredo, finalidade)
+func deriveKey(secret, purpose string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(purpose))
+	return string(mac.Sum(nil))
 }
 
 // função config se configurada ela vai ser inicializada antes da função main
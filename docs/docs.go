@@ -21,58 +21,58 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/products": {
+        "/.well-known/jwks.json": {
+            "get": {
+                "description": "Public keys used to verify the issued JWTs, selected by the kid header. Empty when tokens are signed with HS256",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "JSON Web Key Set",
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/categories": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "get all products",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "List every category as a tree, each root with its children",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "products"
-                ],
-                "summary": "List products",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "page number",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "limit",
-                        "name": "limit",
-                        "in": "query"
-                    }
+                    "categories"
                 ],
+                "summary": "List categories",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/github_com_waanvieira_api-users_internal_entity.Product"
+                                "$ref": "#/definitions/github_com_waanvieira_api-users_internal_entity.Category"
                             }
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/internal_infra_webserver_handlers.Error"
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/internal_infra_webserver_handlers.Error"
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
                         }
                     }
                 }
@@ -83,7 +83,7 @@ const docTemplate = `{
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Create products",
+                "description": "Create a category, at the root or under parent_id",
                 "consumes": [
                     "application/json"
                 ],
@@ -91,56 +91,80 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "products"
+                    "categories"
                 ],
-                "summary": "Create product",
+                "summary": "Create category",
                 "parameters": [
                     {
-                        "description": "product request",
+                        "description": "category request",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_dto.CreateProductInput"
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_dto.CategoryInput"
                         }
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "Created"
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_entity.Category"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/internal_infra_webserver_handlers.Error"
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
                         }
                     }
                 }
             }
         },
-        "/products/{id}": {
+        "/categories/{id}": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Get a product",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Get a category",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "products"
+                    "categories"
                 ],
-                "summary": "Get a product",
+                "summary": "Get a category",
                 "parameters": [
                     {
                         "type": "string",
                         "format": "uuid",
-                        "description": "product ID",
+                        "description": "category ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -150,16 +174,25 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_entity.Product"
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_entity.Category"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
                         }
                     },
                     "404": {
-                        "description": "Not Found"
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/internal_infra_webserver_handlers.Error"
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
                         }
                     }
                 }
@@ -170,7 +203,7 @@ const docTemplate = `{
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Update a product",
+                "description": "Rename a category or move it to another parent, moving it under one of its own subcategories is refused",
                 "consumes": [
                     "application/json"
                 ],
@@ -178,39 +211,69 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "products"
+                    "categories"
                 ],
-                "summary": "Update a product",
+                "summary": "Update a category",
                 "parameters": [
                     {
                         "type": "string",
                         "format": "uuid",
-                        "description": "product ID",
+                        "description": "category ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "product request",
+                        "description": "category request",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_dto.CreateProductInput"
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_dto.CategoryInput"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK"
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_entity.Category"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
                     },
                     "404": {
-                        "description": "Not Found"
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/internal_infra_webserver_handlers.Error"
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
                         }
                     }
                 }
@@ -221,46 +284,66 @@ const docTemplate = `{
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Delete a product",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
+                "description": "Delete a category without subcategories, its products stay in the catalog",
                 "tags": [
-                    "products"
+                    "categories"
                 ],
-                "summary": "Delete a product",
+                "summary": "Delete a category",
                 "parameters": [
                     {
                         "type": "string",
                         "format": "uuid",
-                        "description": "product ID",
+                        "description": "category ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK"
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
                     },
                     "404": {
-                        "description": "Not Found"
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/internal_infra_webserver_handlers.Error"
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
                         }
                     }
                 }
             }
         },
-        "/users": {
-            "post": {
-                "description": "Create user",
+        "/products": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "get all products",
                 "consumes": [
                     "application/json"
                 ],
@@ -268,36 +351,161 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "products"
                 ],
-                "summary": "Create user",
+                "summary": "List products",
                 "parameters": [
                     {
-                        "description": "user request",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_dto.CreateUserInput"
-                        }
+                        "type": "string",
+                        "description": "page number, offset pagination kept for older clients",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "page size, without it (or 0) the server default is used and values above the maximum are reduced to it",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "wrap the products in {data, meta} with page, limit, total and total_pages",
+                        "name": "envelope",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "cursor from X-Next-Cursor, returns the products after it",
+                        "name": "after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "cursor from X-Prev-Cursor, returns the products before it",
+                        "name": "before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "created_at",
+                        "description": "comma separated fields, - for descending, e.g. -price,name; fields: name, price, currency, stock, status, sku, created_at, updated_at; asc and desc sort by created_at",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "only products created by the authenticated user",
+                        "name": "mine",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "only products in the category",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "with category, also products in its subcategories",
+                        "name": "include_subcategories",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "comma separated tags, e.g. promoção,verão",
+                        "name": "tags",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "any",
+                            "all"
+                        ],
+                        "type": "string",
+                        "description": "any (default) returns products with at least one of the tags, all only products with every tag",
+                        "name": "match",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "filter expression, e.g. price gt 10 and name contains 'chair' and created_at ge 2026-01-01",
+                        "name": "filter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the page already cached by the client",
+                        "name": "If-None-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created"
+                    "200": {
+                        "description": "bare array, or dto.ProductListOutput with envelope=true",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_waanvieira_api-users_internal_entity.Product"
+                            }
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "hash of the page"
+                            },
+                            "Last-Modified": {
+                                "type": "string",
+                                "description": "most recent change among the products of the page"
+                            },
+                            "Link": {
+                                "type": "string",
+                                "description": "RFC 8288 links to the next and previous pages"
+                            },
+                            "X-Next-Cursor": {
+                                "type": "string",
+                                "description": "cursor of the next page"
+                            },
+                            "X-Prev-Cursor": {
+                                "type": "string",
+                                "description": "cursor of the previous page"
+                            },
+                            "X-Total-Count": {
+                                "type": "integer",
+                                "description": "products matching the filters"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "page not modified"
+                    },
+                    "400": {
+                        "description": "invalid filter, sort, match, limit or cursor",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/internal_infra_webserver_handlers.Error"
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
                         }
                     }
                 }
-            }
-        },
-        "/users/generate_token": {
+            },
             "post": {
-                "description": "Get a user JWT",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Create products",
                 "consumes": [
                     "application/json"
                 ],
@@ -305,60 +513,1357 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "products"
                 ],
-                "summary": "Get a user JWT",
+                "summary": "Create product",
                 "parameters": [
                     {
-                        "description": "user credentials",
+                        "description": "product request",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_dto_users.GetJWTInput"
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_dto.CreateProductInput"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created"
+                    },
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_dto_users.GetJWTOutput"
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/internal_infra_webserver_handlers.Error"
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/internal_infra_webserver_handlers.Error"
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "github_com_waanvieira_api-users_internal_dto.CreateProductInput": {
-            "type": "object",
-            "properties": {
-                "name": {
-                    "type": "string"
-                },
-                "price": {
-                    "type": "number"
-                }
-            }
         },
-        "github_com_waanvieira_api-users_internal_dto.CreateUserInput": {
-            "type": "object",
-            "properties": {
-                "email": {
-                    "type": "string"
+        "/products/search": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Search the product names and descriptions, most relevant first. Every word must be found, \"quoted text\" is a phrase and a trailing * also matches the words starting with the term. Uses the SQLite FTS5 index when available and LIKE otherwise",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Search products",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "search text, e.g. cadeira ergo*, with phrases between double quotes",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "page size, same default and maximum as GET /products",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "wrap the results in {data, meta} with page, limit, total and total_pages",
+                        "name": "envelope",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "bare array, or dto.ProductSearchOutput with envelope=true",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_waanvieira_api-users_internal_entity.ProductSearchResult"
+                            }
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "RFC 8288 links to the next and previous pages"
+                            },
+                            "X-Total-Count": {
+                                "type": "integer",
+                                "description": "products found"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid q or limit",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/trash": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "List the products in the trash, editors only see their own products and admins see all of them",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "List deleted products",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "page size, same default and maximum as GET /products",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_waanvieira_api-users_internal_entity.Product"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid limit",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/trash/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Remove a product from the trash for good, only admins can do it",
+                "tags": [
+                    "products"
+                ],
+                "summary": "Permanently delete a product",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get a product",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Get a product",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag already cached by the client",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last-Modified already cached by the client",
+                        "name": "If-Modified-Since",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_entity.Product"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "version of the product, send it in If-Match to change it"
+                            },
+                            "Last-Modified": {
+                                "type": "string",
+                                "description": "date of the last change"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "product not modified"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Update a product",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Update a product",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag from GET /products/{id}, required when REQUIRE_IF_MATCH is enabled",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "product request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_dto.CreateProductInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "new version of the product"
+                            },
+                            "Last-Modified": {
+                                "type": "string",
+                                "description": "date of the change"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Move a product to the trash, it can be restored with POST /products/{id}/restore",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Delete a product",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag from GET /products/{id}, required when REQUIRE_IF_MATCH is enabled",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "version of the product in the trash"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Apply a JSON Merge Patch (RFC 7396) to a product. Only the informed fields change, null removes the value and id, owner_id, version and created_at cannot be changed",
+                "consumes": [
+                    "application/merge-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Partially update a product",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag from GET /products/{id}, required when REQUIRE_IF_MATCH is enabled",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "fields to change",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_dto.CreateProductInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_entity.Product"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "new version of the product"
+                            },
+                            "Last-Modified": {
+                                "type": "string",
+                                "description": "date of the change"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{id}/categories": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Replace the categories of a product, an empty list removes all of them",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Set product categories",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag from GET /products/{id}, required when REQUIRE_IF_MATCH is enabled",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "categories of the product",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_dto.ProductCategoriesInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_entity.Product"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "new version of the product"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/products/{id}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Move a product from the trash back to the catalog",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "products"
+                ],
+                "summary": "Restore a deleted product",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "product ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the product in the trash (its version, quoted, as listed by GET /products/trash), required when REQUIRE_IF_MATCH is enabled",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_entity.Product"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "new version of the product"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "Precondition Required",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/tags": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "List the tags in use with how many products have each one, most used first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tags"
+                ],
+                "summary": "List tags",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_waanvieira_api-users_internal_entity.TagCount"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/users": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "List every user, only for admins",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "List users",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "page size, same default and maximum as GET /products",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "asc or desc, by name",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_waanvieira_api-users_internal_dto_users.UserOutput"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid limit",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Create user",
+                "parameters": [
+                    {
+                        "description": "user request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_dto.CreateUserInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/generate_token": {
+            "post": {
+                "description": "Get a user JWT and a refresh token",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get a user JWT",
+                "parameters": [
+                    {
+                        "description": "user credentials",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_dto_users.GetJWTInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_dto_users.GetJWTOutput"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "unknown email or wrong password",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/logout": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Revoke the JWT used in the request and, when informed, every refresh token issued from the same login",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Logout",
+                "parameters": [
+                    {
+                        "description": "refresh token",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_dto_users.LogoutInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get the user of the JWT",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get the authenticated user",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_dto_users.UserOutput"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Update name, email and, when informed, the password of the user of the JWT. A new password revokes every refresh token of the user and the JWT used in the request",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Update the authenticated user",
+                "parameters": [
+                    {
+                        "description": "user request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_dto_users.UpdateUserInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_dto_users.UserOutput"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Delete the user of the JWT and revoke every refresh token of the user and the JWT used in the request",
+                "tags": [
+                    "users"
+                ],
+                "summary": "Delete the authenticated user",
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/refresh": {
+            "post": {
+                "description": "Exchange a refresh token for a new JWT and a new refresh token. The used refresh token stops working, and reusing it revokes every token issued from the same login",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Refresh a user JWT",
+                "parameters": [
+                    {
+                        "description": "refresh token",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_dto_users.RefreshTokenInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_dto_users.GetJWTOutput"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "github_com_waanvieira_api-users_internal_dto.CategoryInput": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "parent_id": {
+                    "description": "Vazio (ou null) deixa a categoria na raiz",
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_waanvieira_api-users_internal_dto.CreateProductInput": {
+            "type": "object",
+            "properties": {
+                "category_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "description": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "price": {
+                    "description": "{\"amount\": \"10.50\", \"currency\": \"BRL\"}, sem a moeda vale BRL",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_pkg_entity.Money"
+                        }
+                    ]
+                },
+                "sku": {
+                    "description": "Opcional, quando vier precisa ser único",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "Vazio cadastra como draft",
+                    "type": "string",
+                    "enum": [
+                        "draft",
+                        "active",
+                        "archived"
+                    ]
+                },
+                "stock": {
+                    "type": "integer"
+                },
+                "tags": {
+                    "description": "Normalizadas em minúsculas, repetidas são ignoradas",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "github_com_waanvieira_api-users_internal_dto.CreateUserInput": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
                 },
                 "name": {
                     "type": "string"
@@ -368,6 +1873,17 @@ const docTemplate = `{
                 }
             }
         },
+        "github_com_waanvieira_api-users_internal_dto.ProductCategoriesInput": {
+            "type": "object",
+            "properties": {
+                "category_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
         "github_com_waanvieira_api-users_internal_dto_users.GetJWTInput": {
             "type": "object",
             "properties": {
@@ -384,33 +1900,285 @@ const docTemplate = `{
             "properties": {
                 "access_token": {
                     "type": "string"
+                },
+                "expires_in": {
+                    "description": "Tempo de vida do access token em segundos",
+                    "type": "integer"
+                },
+                "refresh_token": {
+                    "type": "string"
+                },
+                "token_type": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_waanvieira_api-users_internal_dto_users.LogoutInput": {
+            "type": "object",
+            "properties": {
+                "refresh_token": {
+                    "description": "Opcional, quando enviado revoga também os refresh tokens do mesmo login",
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_waanvieira_api-users_internal_dto_users.RefreshTokenInput": {
+            "type": "object",
+            "properties": {
+                "refresh_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_waanvieira_api-users_internal_dto_users.UpdateUserInput": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "password": {
+                    "description": "Opcional, vazio mantém a senha atual",
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_waanvieira_api-users_internal_dto_users.UserOutput": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_waanvieira_api-users_internal_entity.Category": {
+            "type": "object",
+            "properties": {
+                "children": {
+                    "description": "Preenchido apenas na árvore devolvida pelo GET /categories",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_waanvieira_api-users_internal_entity.Category"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "parent_id": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_waanvieira_api-users_internal_entity.FieldError": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "invalid"
+                },
+                "field": {
+                    "type": "string",
+                    "example": "price"
                 }
             }
         },
         "github_com_waanvieira_api-users_internal_entity.Product": {
             "type": "object",
             "properties": {
+                "categories": {
+                    "description": "Informadas no cadastro e alteradas pelo PUT /products/{id}/categories",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_waanvieira_api-users_internal_entity.Category"
+                    }
+                },
                 "created_at": {
                     "type": "string"
                 },
+                "deleted_at": {
+                    "description": "Preenchido quando o produto vai para a lixeira, o gorm ignora esses produtos nas consultas normais",
+                    "type": "string",
+                    "format": "date-time"
+                },
+                "description": {
+                    "type": "string"
+                },
                 "id": {
                     "type": "string"
                 },
                 "name": {
                     "type": "string"
                 },
+                "owner_id": {
+                    "description": "Usuário que cadastrou o produto, vazio nos produtos criados antes de existir o dono",
+                    "type": "string"
+                },
                 "price": {
+                    "description": "Preço na unidade mínima da moeda (centavos), gravado em price_amount e price_currency",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_pkg_entity.Money"
+                        }
+                    ]
+                },
+                "sku": {
+                    "description": "Código do produto no estoque, opcional mas único entre os produtos (inclusive os da lixeira)",
+                    "type": "string"
+                },
+                "status": {
+                    "enum": [
+                        "draft",
+                        "active",
+                        "archived"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_waanvieira_api-users_internal_entity.ProductStatus"
+                        }
+                    ]
+                },
+                "stock": {
+                    "type": "integer"
+                },
+                "tags": {
+                    "description": "Tags livres, normalizadas, informadas no cadastro e alteradas pelo PUT e PATCH",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "updated_at": {
+                    "description": "Data da última alteração, usada no Last-Modified",
+                    "type": "string"
+                },
+                "version": {
+                    "description": "Incrementada a cada alteração, usada no ETag e no If-Match para uma alteração não sobrescrever a outra",
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_waanvieira_api-users_internal_entity.ProductSearchResult": {
+            "type": "object",
+            "properties": {
+                "highlight": {
+                    "description": "Nome e trecho da descrição com o HTML escapado e os termos encontrados entre \u003cmark\u003e e \u003c/mark\u003e",
+                    "type": "string"
+                },
+                "product": {
+                    "$ref": "#/definitions/github_com_waanvieira_api-users_internal_entity.Product"
+                },
+                "score": {
+                    "description": "Relevância, quanto maior mais relevante; só serve para comparar resultados da mesma busca",
                     "type": "number"
+                },
+                "snippet": {
+                    "type": "string"
                 }
             }
         },
-        "internal_infra_webserver_handlers.Error": {
+        "github_com_waanvieira_api-users_internal_entity.ProductStatus": {
+            "type": "string",
+            "enum": [
+                "draft",
+                "active",
+                "archived"
+            ],
+            "x-enum-varnames": [
+                "ProductStatusDraft",
+                "ProductStatusActive",
+                "ProductStatusArchived"
+            ]
+        },
+        "github_com_waanvieira_api-users_internal_entity.TagCount": {
             "type": "object",
             "properties": {
-                "message": {
+                "count": {
+                    "type": "integer"
+                },
+                "name": {
                     "type": "string"
                 }
             }
+        },
+        "github_com_waanvieira_api-users_internal_infra_webserver_problem.Problem": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Código estável para o cliente tratar o erro sem depender da mensagem",
+                    "type": "string",
+                    "example": "not_found"
+                },
+                "detail": {
+                    "type": "string",
+                    "example": "record not found"
+                },
+                "errors": {
+                    "description": "Campos que falharam na validação, apenas no code \"validation_failed\"",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_waanvieira_api-users_internal_entity.FieldError"
+                    }
+                },
+                "instance": {
+                    "description": "Caminho da requisição que gerou o erro",
+                    "type": "string",
+                    "example": "/products/4f1c2a8e-1d2b-4c4f-9a8e-3b2d1c0f9e7a"
+                },
+                "position": {
+                    "description": "Posição (a partir de 1) e trecho do ?filter= com problema, apenas no code \"invalid_filter\"",
+                    "type": "integer",
+                    "example": 7
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 404
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Not Found"
+                },
+                "token": {
+                    "type": "string",
+                    "example": "greater"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "about:blank"
+                }
+            }
+        },
+        "github_com_waanvieira_api-users_pkg_entity.Money": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "string",
+                    "example": "10.50"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "BRL"
+                }
+            }
         }
     },
     "securityDefinitions": {
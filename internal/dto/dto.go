@@ -1,8 +1,20 @@
 package dto
 
+import "github.com/waanvieira/api-users/pkg/entity"
+
 type CreateProductInput struct {
-	Name  string  `json:"name"`
-	Price float64 `json:"price"`
+	Name string `json:"name"`
+	// {"amount": "10.50", "currency": "BRL"}, sem a moeda vale BRL
+	Price       entity.Money `json:"price"`
+	Description string       `json:"description"`
+	// Opcional, quando vier precisa ser único
+	SKU   string `json:"sku"`
+	Stock int64  `json:"stock"`
+	// Vazio cadastra como draft
+	Status      string   `json:"status" enums:"draft,active,archived"`
+	CategoryIDs []string `json:"category_ids"`
+	// Normalizadas em minúsculas, repetidas são ignoradas
+	Tags []string `json:"tags"`
 }
 
 type CreateUserInput struct {
@@ -19,3 +31,13 @@ type GetJWTInput struct {
 // type GetJWTOutput struct {
 // 	AccessToken string `json:"access_token"`
 // }
+
+type CategoryInput struct {
+	Name string `json:"name"`
+	// Vazio (ou null) deixa a categoria na raiz
+	ParentID *string `json:"parent_id"`
+}
+
+type ProductCategoriesInput struct {
+	CategoryIDs []string `json:"category_ids"`
+}
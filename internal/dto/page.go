@@ -0,0 +1,27 @@
+package dto
+
+import "github.com/waanvieira/api-users/internal/entity"
+
+// PageMeta são os metadados da página no envelope das listagens
+type PageMeta struct {
+	// Número da página, ausente nas páginas por cursor depois da primeira
+	Page       int   `json:"page,omitempty" example:"1"`
+	Limit      int   `json:"limit" example:"20"`
+	Total      int64 `json:"total" example:"57"`
+	TotalPages int64 `json:"total_pages" example:"3"`
+	// Cursores para o ?after= e o ?before=, só nas páginas por cursor
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// ProductListOutput é a resposta do GET /products com ?envelope=true
+type ProductListOutput struct {
+	Data []entity.Product `json:"data"`
+	Meta PageMeta         `json:"meta"`
+}
+
+// ProductSearchOutput é a resposta do GET /products/search com ?envelope=true
+type ProductSearchOutput struct {
+	Data []entity.ProductSearchResult `json:"data"`
+	Meta PageMeta                     `json:"meta"`
+}
@@ -6,5 +6,18 @@ type GetJWTInput struct {
 }
 
 type GetJWTOutput struct {
-	AccessToken string `json:"access_token"`
-}
\ No newline at end of file
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	// Tempo de vida do access token em segundos
+	ExpiresIn int `json:"expires_in"`
+}
+
+type RefreshTokenInput struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type LogoutInput struct {
+	// Opcional, quando enviado revoga também os refresh tokens do mesmo login
+	RefreshToken string `json:"refresh_token"`
+}
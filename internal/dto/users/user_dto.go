@@ -0,0 +1,16 @@
+package users
+
+// UserOutput é o usuário devolvido pela api, nunca inclui o hash da senha
+type UserOutput struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+type UpdateUserInput struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	// Opcional, vazio mantém a senha atual
+	Password string `json:"password"`
+}
@@ -0,0 +1,89 @@
+package entity
+
+import (
+	"errors"
+	"time"
+	"unicode/utf8"
+
+	"github.com/waanvieira/api-users/pkg/entity"
+)
+
+// Tamanho máximo do nome da categoria em caracteres
+const MaxCategoryNameLength = 100
+
+var (
+	ErrCategoryNameTooLong    = errors.New("category name must have at most 100 characters")
+	ErrCategoryCycle          = errors.New("a category cannot be moved under itself or one of its subcategories")
+	ErrCategoryParentNotFound = errors.New("parent category not found")
+	ErrCategoryNotFound       = errors.New("category not found")
+	ErrCategoryHasChildren    = errors.New("category has subcategories, move or delete them first")
+)
+
+// Category organiza o catálogo em árvore, uma categoria sem ParentID é uma raiz
+type Category struct {
+	ID       entity.ID  `json:"id"`
+	Name     string     `json:"name" gorm:"size:100;not null"`
+	ParentID *entity.ID `json:"parent_id" gorm:"index"`
+	// Preenchido apenas na árvore devolvida pelo GET /categories
+	Children  []Category `json:"children,omitempty" gorm:"-"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+func NewCategory(name string, parentID *entity.ID) (*Category, error) {
+	now := time.Now()
+	category := &Category{
+		ID:        entity.NewID(),
+		Name:      name,
+		ParentID:  parentID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := category.Validate(); err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// Validate verifica as regras da própria categoria, a existência do pai e os ciclos são verificados no banco
+func (c *Category) Validate() error {
+	var errs ValidationErrors
+	if c.ID.String() == "" {
+		errs.Add("id", CodeRequired, ErrIDIsRequired)
+	}
+	if c.Name == "" {
+		errs.Add("name", CodeRequired, ErrNameIsRequired)
+	} else if utf8.RuneCountInString(c.Name) > MaxCategoryNameLength {
+		errs.Add("name", CodeTooLong, ErrCategoryNameTooLong)
+	}
+	if c.ParentID != nil && *c.ParentID == c.ID {
+		errs.Add("parent_id", CodeInvalid, ErrCategoryCycle)
+	}
+	return errs.Err()
+}
+
+// BuildCategoryTree monta a árvore a partir da lista de categorias, devolvendo as raízes com os filhos preenchidos
+// Uma categoria cujo pai não está na lista vira raiz
+func BuildCategoryTree(categories []Category) []Category {
+	children := make(map[entity.ID][]Category)
+	known := make(map[entity.ID]bool, len(categories))
+	for _, c := range categories {
+		known[c.ID] = true
+	}
+	var roots []Category
+	for _, c := range categories {
+		if c.ParentID != nil && known[*c.ParentID] {
+			children[*c.ParentID] = append(children[*c.ParentID], c)
+		} else {
+			roots = append(roots, c)
+		}
+	}
+	var attach func(nodes []Category) []Category
+	attach = func(nodes []Category) []Category {
+		for i := range nodes {
+			nodes[i].Children = attach(children[nodes[i].ID])
+		}
+		return nodes
+	}
+	return attach(roots)
+}
@@ -0,0 +1,51 @@
+package entity
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/waanvieira/api-users/pkg/entity"
+)
+
+func TestNewCategory(t *testing.T) {
+	root, err := NewCategory("Roupas", nil)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, root.ID)
+	assert.Nil(t, root.ParentID)
+
+	child, err := NewCategory("Camisetas", &root.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, root.ID, *child.ParentID)
+
+	_, err = NewCategory("", nil)
+	assert.ErrorIs(t, err, ErrNameIsRequired)
+	_, err = NewCategory(strings.Repeat("a", MaxCategoryNameLength+1), nil)
+	assert.ErrorIs(t, err, ErrCategoryNameTooLong)
+}
+
+func TestCategoryCannotBeItsOwnParent(t *testing.T) {
+	category, _ := NewCategory("Roupas", nil)
+	category.ParentID = &category.ID
+	assert.ErrorIs(t, category.Validate(), ErrCategoryCycle)
+}
+
+func TestBuildCategoryTree(t *testing.T) {
+	clothes, _ := NewCategory("Roupas", nil)
+	shirts, _ := NewCategory("Camisetas", &clothes.ID)
+	polos, _ := NewCategory("Polo", &shirts.ID)
+	books, _ := NewCategory("Livros", nil)
+	missing := entity.NewID()
+	orphan, _ := NewCategory("Sem pai", &missing)
+
+	tree := BuildCategoryTree([]Category{*polos, *books, *clothes, *shirts, *orphan})
+	assert.Len(t, tree, 3)
+	names := []string{tree[0].Name, tree[1].Name, tree[2].Name}
+	assert.Equal(t, []string{"Livros", "Roupas", "Sem pai"}, names)
+
+	assert.Len(t, tree[1].Children, 1)
+	assert.Equal(t, "Camisetas", tree[1].Children[0].Name)
+	assert.Len(t, tree[1].Children[0].Children, 1)
+	assert.Equal(t, "Polo", tree[1].Children[0].Children[0].Name)
+	assert.Empty(t, tree[0].Children)
+}
@@ -2,34 +2,110 @@ package entity
 
 import (
 	"errors"
+	"regexp"
 	"time"
+	"unicode/utf8"
 
 	"github.com/waanvieira/api-users/pkg/entity"
+	"gorm.io/gorm"
 	// "github.com/waanvieira/api-Products/pkg/entity"
 )
 
 var (
-	ErrIDIsRequired    = errors.New("Id is required")
-	ErrInvalidID       = errors.New("invalid id")
-	ErrInvalidName     = errors.New("Name is required")
-	ErrNameIsRequired  = errors.New("Name is required")
-	ErrPriceIsRequired = errors.New("Price is required")
-	ErrInvalidPrice    = errors.New("invalid price")
+	ErrIDIsRequired       = errors.New("Id is required")
+	ErrInvalidID          = errors.New("invalid id")
+	ErrInvalidName        = errors.New("Name is required")
+	ErrNameIsRequired     = errors.New("Name is required")
+	ErrPriceIsRequired    = errors.New("Price is required")
+	ErrInvalidPrice       = errors.New("invalid price")
+	ErrDescriptionTooLong = errors.New("description must have at most 2000 characters")
+	ErrInvalidSKU         = errors.New("invalid sku, use up to 64 letters, digits, '.', '_' or '-'")
+	ErrInvalidStock       = errors.New("stock cannot be negative")
+	ErrSKUAlreadyExists   = errors.New("sku already used by another product")
+	ErrNotProductOwner    = errors.New("only the owner of the product or an admin can change it")
+	// O produto foi alterado por outra requisição depois da versão que o cliente leu
+	ErrVersionConflict = errors.New("product was changed by another request")
 )
 
+const MaxDescriptionLength = 2000
+
+// O SKU é um código curto, sem espaços, que começa com letra ou número
+var skuPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
 type Product struct {
-	ID        entity.ID `json:"id"`
-	Name      string    `json:"name"`
-	Price     float64   `json:"price"`
+	ID   entity.ID `json:"id"`
+	Name string    `json:"name"`
+	// Preço na unidade mínima da moeda (centavos), gravado em price_amount e price_currency
+	Price       entity.Money `json:"price" gorm:"embedded;embeddedPrefix:price_"`
+	Description string       `json:"description" gorm:"size:2000;not null;default:''"`
+	// Código do produto no estoque, opcional mas único entre os produtos (inclusive os da lixeira)
+	SKU    *string       `json:"sku" gorm:"size:64;uniqueIndex:idx_products_sku"`
+	Stock  int64         `json:"stock" gorm:"not null;default:0"`
+	Status ProductStatus `json:"status" gorm:"size:20;not null;default:draft" enums:"draft,active,archived"`
+	// Informadas no cadastro e alteradas pelo PUT /products/{id}/categories
+	Categories []Category `json:"categories" gorm:"many2many:product_categories"`
+	// Tags livres, normalizadas, informadas no cadastro e alteradas pelo PUT e PATCH
+	Tags []Tag `json:"tags" gorm:"many2many:product_tags" swaggertype:"array,string"`
+	// Usuário que cadastrou o produto, vazio nos produtos criados antes de existir o dono
+	OwnerID *entity.ID `json:"owner_id" gorm:"index"`
+	// Incrementada a cada alteração, usada no ETag e no If-Match para uma alteração não sobrescrever a outra
+	Version   int64     `json:"version" gorm:"not null;default:1"`
 	CreatedAt time.Time `json:"created_at"`
+	// Data da última alteração, usada no Last-Modified
+	UpdatedAt time.Time `json:"updated_at"`
+	// Preenchido quando o produto vai para a lixeira, o gorm ignora esses produtos nas consultas normais
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index" swaggertype:"string" format:"date-time"`
+}
+
+// ProductOption preenche os campos opcionais do produto no NewProduct
+type ProductOption func(*Product)
+
+func WithDescription(description string) ProductOption {
+	return func(p *Product) { p.Description = description }
 }
 
-func NewProduct(name string, price float64) (*Product, error) {
+// WithSKU informa o SKU, vazio deixa o produto sem SKU
+func WithSKU(sku string) ProductOption {
+	return func(p *Product) {
+		if sku == "" {
+			p.SKU = nil
+			return
+		}
+		p.SKU = &sku
+	}
+}
+
+func WithStock(stock int64) ProductOption {
+	return func(p *Product) { p.Stock = stock }
+}
+
+func WithStatus(status ProductStatus) ProductOption {
+	return func(p *Product) { p.Status = status }
+}
+
+func WithCategories(categories ...Category) ProductOption {
+	return func(p *Product) { p.Categories = categories }
+}
+
+// WithTags normaliza as tags e remove as repetidas
+func WithTags(names ...string) ProductOption {
+	return func(p *Product) { p.Tags = NewTags(names...) }
+}
+
+// NewProduct cria o produto como rascunho e sem estoque, as opções mudam esses valores
+func NewProduct(name string, price entity.Money, options ...ProductOption) (*Product, error) {
+	now := time.Now()
 	product := &Product{
 		ID:        entity.NewID(),
 		Name:      name,
 		Price:     price,
-		CreatedAt: time.Now(),
+		Status:    ProductStatusDraft,
+		Version:   1,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	for _, option := range options {
+		option(product)
 	}
 
 	err := product.Validate()
@@ -40,26 +116,102 @@ func NewProduct(name string, price float64) (*Product, error) {
 	return product, nil
 }
 
+// Validate verifica todas as regras e devolve um ValidationErrors com cada campo que falhou
 func (p *Product) Validate() error {
+	var errs ValidationErrors
 	if p.ID.String() == "" {
-		return ErrIDIsRequired
+		errs.Add("id", CodeRequired, ErrIDIsRequired)
+	} else if _, err := entity.ParseID(p.ID.String()); err != nil {
+		errs.Add("id", CodeInvalid, ErrInvalidID)
 	}
 
-	if _, err := entity.ParseID(p.ID.String()); err != nil {
-		return ErrInvalidID
+	if p.Name == "" {
+		errs.Add("name", CodeRequired, ErrNameIsRequired)
 	}
 
-	if p.Name == "" {
-		return ErrNameIsRequired
+	if p.Price.Amount == 0 {
+		errs.Add("price", CodeRequired, ErrPriceIsRequired)
+	} else if p.Price.Amount < 0 {
+		errs.Add("price", CodeInvalid, ErrInvalidPrice)
+	}
+	if p.Price.Amount != 0 {
+		if err := p.Price.Validate(); err != nil {
+			errs.Add("price", CodeInvalid, err)
+		}
 	}
 
-	if p.Price == 0 {
-		return ErrPriceIsRequired
+	if utf8.RuneCountInString(p.Description) > MaxDescriptionLength {
+		errs.Add("description", CodeTooLong, ErrDescriptionTooLong)
 	}
 
-	if p.Price < 0 {
-		return ErrInvalidPrice
+	if p.SKU != nil && !skuPattern.MatchString(*p.SKU) {
+		errs.Add("sku", CodeInvalid, ErrInvalidSKU)
 	}
 
-	return nil
+	if p.Stock < 0 {
+		errs.Add("stock", CodeInvalid, ErrInvalidStock)
+	}
+
+	if len(p.Tags) > MaxProductTags {
+		errs.Add("tags", CodeInvalid, ErrTooManyTags)
+	}
+	for _, tag := range p.Tags {
+		if !tag.IsValid() {
+			errs.Add("tags", CodeInvalid, ErrInvalidTag)
+			break
+		}
+	}
+
+	if p.Status == "" {
+		errs.Add("status", CodeRequired, ErrInvalidStatus)
+	} else if !p.Status.IsValid() {
+		errs.Add("status", CodeInvalid, ErrInvalidStatus)
+	}
+
+	return errs.Err()
+}
+
+// ValidateChange valida o produto alterado e garante que id, dono, data de cadastro e versão continuam os mesmos do atual
+func (p *Product) ValidateChange(current *Product) error {
+	var errs ValidationErrors
+	if p.ID != current.ID {
+		errs.Add("id", CodeImmutable, ErrImmutableField)
+	}
+	if !sameOwner(p.OwnerID, current.OwnerID) {
+		errs.Add("owner_id", CodeImmutable, ErrImmutableField)
+	}
+	if !p.CreatedAt.Equal(current.CreatedAt) {
+		errs.Add("created_at", CodeImmutable, ErrImmutableField)
+	}
+	// A versão só muda ao gravar, quem controla a concorrência é o If-Match
+	if p.Version != current.Version {
+		errs.Add("version", CodeImmutable, ErrImmutableField)
+	}
+	var validation ValidationErrors
+	if errors.As(p.Validate(), &validation) {
+		errs = append(errs, validation...)
+	}
+	return errs.Err()
+}
+
+func sameOwner(a, b *entity.ID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// IsOwnedBy indica se o produto foi cadastrado pelo usuário informado
+func (p *Product) IsOwnedBy(userID string) bool {
+	return p.OwnerID != nil && p.OwnerID.String() == userID
+}
+
+// ProductSearchResult é um produto encontrado pela busca com os trechos onde os termos aparecem
+type ProductSearchResult struct {
+	Product Product `json:"product"`
+	// Relevância, quanto maior mais relevante; só serve para comparar resultados da mesma busca
+	Score float64 `json:"score"`
+	// Nome e trecho da descrição com o HTML escapado e os termos encontrados entre <mark> e </mark>
+	Highlight string `json:"highlight"`
+	Snippet   string `json:"snippet"`
 }
@@ -0,0 +1,35 @@
+package entity
+
+import "errors"
+
+var ErrInvalidStatus = errors.New("invalid status, use draft, active or archived")
+
+// ProductStatus é o ciclo de vida do produto no catálogo
+// draft (rascunho, padrão no cadastro) > active (à venda) > archived (fora de linha)
+type ProductStatus string
+
+const (
+	ProductStatusDraft    ProductStatus = "draft"
+	ProductStatusActive   ProductStatus = "active"
+	ProductStatusArchived ProductStatus = "archived"
+)
+
+func ParseProductStatus(s string) (ProductStatus, error) {
+	status := ProductStatus(s)
+	if !status.IsValid() {
+		return "", ErrInvalidStatus
+	}
+	return status, nil
+}
+
+func (s ProductStatus) IsValid() bool {
+	switch s {
+	case ProductStatusDraft, ProductStatusActive, ProductStatusArchived:
+		return true
+	}
+	return false
+}
+
+func (s ProductStatus) String() string {
+	return string(s)
+}
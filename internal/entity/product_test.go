@@ -1,13 +1,16 @@
 package entity
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/waanvieira/api-users/pkg/entity"
 )
 
 func TestNewProduct(t *testing.T) {
-	product, err := NewProduct("test", 15)
+	product, err := NewProduct("test", entity.MustParseMoney("15", "BRL"))
 	// Verifica que o erro está em branco
 	assert.Nil(t, err)
 	assert.NotNil(t, product)
@@ -15,32 +18,153 @@ func TestNewProduct(t *testing.T) {
 	assert.NotEmpty(t, product.Name)
 	assert.NotEmpty(t, product.CreatedAt)
 	assert.Equal(t, "test", product.Name)
-	assert.Equal(t, 15.0, product.Price)
+	assert.Equal(t, entity.Money{Amount: 1500, Currency: "BRL"}, product.Price)
 }
 
 func TestProductWhenNameIsRequired(t *testing.T) {
 	// Criando um produto com nome zero para disparar o erro
-	p, err := NewProduct("", 0)
+	p, err := NewProduct("", entity.Money{})
 	// Testando se a variavel está em branco, o inverso do test anterior que verificamos se o erro está em branco
 	assert.Nil(t, p)
 	// Verifica qual é o erro que apresentou, nesse caso tem que ser o erro de nome obrigatório
-	assert.Equal(t, ErrNameIsRequired, err)
+	assert.ErrorIs(t, err, ErrNameIsRequired)
 }
 
 func TestProductWhenPriceIsRequiredAndInvalid(t *testing.T) {
 	// Criando um produto com nome zero para disparar o erro
-	p, err := NewProduct("test", 0)
+	p, err := NewProduct("test", entity.Money{})
 	// Testando se a variavel está em branco, o inverso do test anterior que verificamos se o erro está em branco
 	assert.Nil(t, p)
 	// Verifica qual é o erro que apresentou, nesse caso tem que ser o erro de nome obrigatório
-	assert.Equal(t, ErrPriceIsRequired, err)
+	assert.ErrorIs(t, err, ErrPriceIsRequired)
 }
 
 func TestProductWhenPriceIsInvalid(t *testing.T) {
-	p, err := NewProduct("test", -10)
+	p, err := NewProduct("test", entity.MustParseMoney("-10", "BRL"))
 	// Testando se a variavel está em branco, o inverso do test anterior que verificamos se o erro está em branco
 	assert.Nil(t, p)
 	// Verifica qual é o erro que apresentou, nesse caso tem que ser o erro de nome obrigatório
-	assert.Equal(t, ErrInvalidPrice, err)
+	assert.ErrorIs(t, err, ErrInvalidPrice)
 
 }
+
+func TestProductIsOwnedBy(t *testing.T) {
+	p, _ := NewProduct("test", entity.MustParseMoney("15", "BRL"))
+	// Produto sem dono não pertence a ninguém
+	assert.False(t, p.IsOwnedBy(""))
+
+	ownerID := entity.NewID()
+	p.OwnerID = &ownerID
+	assert.True(t, p.IsOwnedBy(ownerID.String()))
+	assert.False(t, p.IsOwnedBy(entity.NewID().String()))
+}
+
+func TestProductValidateReturnsEveryField(t *testing.T) {
+	p, err := NewProduct("", entity.MustParseMoney("-10", "BRL"))
+	assert.Nil(t, p)
+
+	var errs ValidationErrors
+	assert.ErrorAs(t, err, &errs)
+	assert.Equal(t, ValidationErrors{
+		{Field: "name", Code: CodeRequired, Err: ErrNameIsRequired},
+		{Field: "price", Code: CodeInvalid, Err: ErrInvalidPrice},
+	}, errs)
+}
+
+func TestProductValidateChange(t *testing.T) {
+	current, _ := NewProduct("test", entity.MustParseMoney("15", "BRL"))
+	ownerID := entity.NewID()
+	current.OwnerID = &ownerID
+
+	changed := *current
+	changed.Name = "changed"
+	assert.Nil(t, changed.ValidateChange(current))
+
+	otherOwner := entity.NewID()
+	changed.ID = entity.NewID()
+	changed.OwnerID = &otherOwner
+	changed.CreatedAt = current.CreatedAt.Add(time.Hour)
+	changed.Version = current.Version + 1
+	changed.Price = entity.MustParseMoney("-1", "BRL")
+
+	var errs ValidationErrors
+	assert.ErrorAs(t, changed.ValidateChange(current), &errs)
+	assert.Equal(t, ValidationErrors{
+		{Field: "id", Code: CodeImmutable, Err: ErrImmutableField},
+		{Field: "owner_id", Code: CodeImmutable, Err: ErrImmutableField},
+		{Field: "created_at", Code: CodeImmutable, Err: ErrImmutableField},
+		{Field: "version", Code: CodeImmutable, Err: ErrImmutableField},
+		{Field: "price", Code: CodeInvalid, Err: ErrInvalidPrice},
+	}, errs)
+
+	// Remover o dono também é alterar o dono
+	changed = *current
+	changed.OwnerID = nil
+	assert.ErrorIs(t, changed.ValidateChange(current), ErrImmutableField)
+}
+
+func TestNewProductDefaultsAndOptions(t *testing.T) {
+	p, err := NewProduct("test", entity.MustParseMoney("15", "BRL"))
+	assert.NoError(t, err)
+	assert.Equal(t, ProductStatusDraft, p.Status)
+	assert.Nil(t, p.SKU)
+	assert.Zero(t, p.Stock)
+
+	p, err = NewProduct("test", entity.MustParseMoney("15", "BRL"),
+		WithDescription("a product"),
+		WithSKU("ABC-123"),
+		WithStock(7),
+		WithStatus(ProductStatusActive),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "a product", p.Description)
+	assert.Equal(t, "ABC-123", *p.SKU)
+	assert.Equal(t, int64(7), p.Stock)
+	assert.Equal(t, ProductStatusActive, p.Status)
+
+	// SKU vazio é o mesmo que não informar
+	p, err = NewProduct("test", entity.MustParseMoney("15", "BRL"), WithSKU(""))
+	assert.NoError(t, err)
+	assert.Nil(t, p.SKU)
+}
+
+func TestProductCatalogFieldsAreValidated(t *testing.T) {
+	p, err := NewProduct("test", entity.MustParseMoney("15", "BRL"),
+		WithDescription(strings.Repeat("a", MaxDescriptionLength+1)),
+		WithSKU("ABC 123"),
+		WithStock(-1),
+		WithStatus("sold"),
+	)
+	assert.Nil(t, p)
+	assert.ErrorIs(t, err, ErrDescriptionTooLong)
+	assert.ErrorIs(t, err, ErrInvalidSKU)
+	assert.ErrorIs(t, err, ErrInvalidStock)
+	assert.ErrorIs(t, err, ErrInvalidStatus)
+
+	var validation ValidationErrors
+	assert.ErrorAs(t, err, &validation)
+	assert.Len(t, validation, 4)
+
+	// A descrição conta caracteres, não bytes
+	_, err = NewProduct("test", entity.MustParseMoney("15", "BRL"), WithDescription(strings.Repeat("ç", MaxDescriptionLength)))
+	assert.NoError(t, err)
+}
+
+func TestParseProductStatus(t *testing.T) {
+	status, err := ParseProductStatus("archived")
+	assert.NoError(t, err)
+	assert.Equal(t, ProductStatusArchived, status)
+
+	_, err = ParseProductStatus("")
+	assert.ErrorIs(t, err, ErrInvalidStatus)
+}
+
+func TestProductPriceCurrencyIsValidated(t *testing.T) {
+	p, err := NewProduct("test", entity.Money{Amount: 1000, Currency: "XYZ"})
+	assert.Nil(t, p)
+	assert.ErrorIs(t, err, entity.ErrUnsupportedCurrency)
+
+	p, err = NewProduct("test", entity.MustParseMoney("1500", "JPY"))
+	assert.NoError(t, err)
+	assert.Equal(t, "1500 JPY", p.Price.String())
+}
@@ -0,0 +1,67 @@
+package entity
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/waanvieira/api-users/pkg/entity"
+)
+
+var (
+	ErrRefreshTokenInvalid = errors.New("invalid refresh token")
+	ErrRefreshTokenExpired = errors.New("refresh token expired")
+	ErrRefreshTokenRevoked = errors.New("refresh token revoked")
+	// Token que já foi trocado sendo usado de novo, sinal de que ele pode ter vazado
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+)
+
+// RefreshToken guarda apenas o hash do token opaco que entregamos para o cliente
+// Todos os tokens gerados a partir do mesmo login compartilham o FamilyID, assim conseguimos revogar a família inteira
+type RefreshToken struct {
+	ID        entity.ID  `json:"id"`
+	UserID    entity.ID  `json:"user_id" gorm:"index"`
+	FamilyID  entity.ID  `json:"family_id" gorm:"index"`
+	TokenHash string     `json:"-" gorm:"size:64;uniqueIndex"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	RotatedAt *time.Time `json:"rotated_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
+
+// NewRefreshToken cria o registro e devolve também o token em texto puro, que só existe nesse momento
+func NewRefreshToken(userID, familyID entity.ID, expiresIn time.Duration) (*RefreshToken, string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	now := time.Now()
+
+	return &RefreshToken{
+		ID:        entity.NewID(),
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: HashRefreshToken(token),
+		ExpiresAt: now.Add(expiresIn),
+		CreatedAt: now,
+	}, token, nil
+}
+
+// HashRefreshToken gera o hash que salvamos no banco, o token original nunca é gravado
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (t *RefreshToken) IsExpired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
+
+// IsUsed indica que o token já foi trocado ou revogado e não pode mais ser usado
+func (t *RefreshToken) IsUsed() bool {
+	return t.RotatedAt != nil || t.RevokedAt != nil
+}
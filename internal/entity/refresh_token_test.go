@@ -0,0 +1,42 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/waanvieira/api-users/pkg/entity"
+)
+
+func TestNewRefreshToken(t *testing.T) {
+	userID := entity.NewID()
+	familyID := entity.NewID()
+	token, raw, err := NewRefreshToken(userID, familyID, time.Hour)
+	assert.Nil(t, err)
+	assert.NotNil(t, token)
+	assert.NotEmpty(t, raw)
+	assert.Equal(t, userID, token.UserID)
+	assert.Equal(t, familyID, token.FamilyID)
+	// O token em texto puro nunca fica salvo na entidade, apenas o hash
+	assert.NotEqual(t, raw, token.TokenHash)
+	assert.Equal(t, HashRefreshToken(raw), token.TokenHash)
+	assert.False(t, token.IsExpired(time.Now()))
+	assert.True(t, token.IsExpired(time.Now().Add(2*time.Hour)))
+	assert.False(t, token.IsUsed())
+
+	_, other, err := NewRefreshToken(userID, familyID, time.Hour)
+	assert.Nil(t, err)
+	assert.NotEqual(t, raw, other)
+}
+
+func TestRefreshToken_IsUsed(t *testing.T) {
+	token, _, err := NewRefreshToken(entity.NewID(), entity.NewID(), time.Hour)
+	assert.Nil(t, err)
+	now := time.Now()
+	token.RotatedAt = &now
+	assert.True(t, token.IsUsed())
+
+	token.RotatedAt = nil
+	token.RevokedAt = &now
+	assert.True(t, token.IsUsed())
+}
@@ -0,0 +1,23 @@
+package entity
+
+import "time"
+
+// RevokedToken guarda o jti (id único do JWT) dos access tokens que não podem mais ser usados
+// O registro só precisa existir até o token expirar, depois disso o próprio exp já invalida o token
+type RevokedToken struct {
+	JTI       string    `json:"jti" gorm:"primaryKey;size:64"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"index"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewRevokedToken(jti string, expiresAt time.Time) *RevokedToken {
+	return &RevokedToken{
+		JTI:       jti,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}
+}
+
+func (t *RevokedToken) IsExpired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
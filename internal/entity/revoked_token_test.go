@@ -0,0 +1,18 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRevokedToken(t *testing.T) {
+	expiresAt := time.Now().Add(time.Minute)
+	token := NewRevokedToken("jti-test", expiresAt)
+	assert.Equal(t, "jti-test", token.JTI)
+	assert.Equal(t, expiresAt, token.ExpiresAt)
+	assert.NotEmpty(t, token.CreatedAt)
+	assert.False(t, token.IsExpired(time.Now()))
+	assert.True(t, token.IsExpired(expiresAt))
+}
@@ -0,0 +1,44 @@
+package entity
+
+import "errors"
+
+var ErrInvalidRole = errors.New("invalid role")
+
+// Role é o perfil do usuário, cada perfil inclui as permissões dos perfis abaixo dele
+// admin > editor > viewer
+type Role string
+
+const (
+	RoleViewer Role = "viewer"
+	RoleEditor Role = "editor"
+	RoleAdmin  Role = "admin"
+)
+
+var roleLevels = map[Role]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+func ParseRole(s string) (Role, error) {
+	role := Role(s)
+	if !role.IsValid() {
+		return "", ErrInvalidRole
+	}
+	return role, nil
+}
+
+func (r Role) IsValid() bool {
+	_, ok := roleLevels[r]
+	return ok
+}
+
+// Includes indica se o perfil tem pelo menos as permissões do perfil informado, ex: admin inclui editor
+func (r Role) Includes(required Role) bool {
+	level, ok := roleLevels[r]
+	return ok && level >= roleLevels[required]
+}
+
+func (r Role) String() string {
+	return string(r)
+}
@@ -0,0 +1,80 @@
+package entity
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// Tamanho máximo da tag em caracteres, depois de normalizada
+	MaxTagLength = 50
+	// Quantidade máxima de tags por produto
+	MaxProductTags = 20
+)
+
+var (
+	ErrInvalidTag  = errors.New("tags must have 1 to 50 characters and cannot contain commas")
+	ErrTooManyTags = errors.New("a product can have at most 20 tags")
+)
+
+// Tag é uma etiqueta livre do produto, o nome normalizado é a própria chave
+// No JSON a tag é apenas o nome: "tags": ["promoção", "verão"]
+type Tag struct {
+	Name string `gorm:"primaryKey;size:50"`
+}
+
+// TagCount é a tag com a quantidade de produtos que a usam
+type TagCount struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// NormalizeTagName deixa a tag em minúsculas, sem espaços nas pontas e com um único espaço entre as palavras
+// Assim " Promoção  Verão" e "promoção verão" são a mesma tag
+func NormalizeTagName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// NewTags normaliza os nomes e remove as repetidas, mantendo a ordem em que vieram
+func NewTags(names ...string) []Tag {
+	tags := make([]Tag, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = NormalizeTagName(name)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		tags = append(tags, Tag{Name: name})
+	}
+	return tags
+}
+
+// UniqueTags normaliza e remove as repetidas de uma lista que veio do JSON
+func UniqueTags(tags []Tag) []Tag {
+	names := make([]string, len(tags))
+	for i, t := range tags {
+		names[i] = t.Name
+	}
+	return NewTags(names...)
+}
+
+func (t Tag) IsValid() bool {
+	return t.Name != "" && utf8.RuneCountInString(t.Name) <= MaxTagLength && !strings.Contains(t.Name, ",")
+}
+
+func (t Tag) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.Name)
+}
+
+// UnmarshalJSON lê a tag como texto já normalizado, a validação fica no Validate do produto
+func (t *Tag) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	t.Name = NormalizeTagName(name)
+	return nil
+}
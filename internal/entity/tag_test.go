@@ -0,0 +1,55 @@
+package entity
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/waanvieira/api-users/pkg/entity"
+)
+
+func TestNormalizeTagName(t *testing.T) {
+	assert.Equal(t, "promoção", NormalizeTagName("  PROMOÇÃO "))
+	assert.Equal(t, "dia das mães", NormalizeTagName("Dia  das\tMães"))
+	assert.Equal(t, "", NormalizeTagName("   "))
+}
+
+func TestNewTagsRemovesDuplicates(t *testing.T) {
+	tags := NewTags("Verão", "promoção", " verão ", "PROMOÇÃO")
+	assert.Equal(t, []Tag{{Name: "verão"}, {Name: "promoção"}}, tags)
+}
+
+func TestTagIsValid(t *testing.T) {
+	assert.True(t, Tag{Name: "verão"}.IsValid())
+	assert.False(t, Tag{Name: ""}.IsValid())
+	assert.False(t, Tag{Name: "a,b"}.IsValid())
+	assert.True(t, Tag{Name: strings.Repeat("ã", MaxTagLength)}.IsValid())
+	assert.False(t, Tag{Name: strings.Repeat("a", MaxTagLength+1)}.IsValid())
+}
+
+func TestTagJSON(t *testing.T) {
+	var tags []Tag
+	assert.NoError(t, json.Unmarshal([]byte(`[" Verão ", "promoção"]`), &tags))
+	assert.Equal(t, []Tag{{Name: "verão"}, {Name: "promoção"}}, tags)
+
+	data, err := json.Marshal(tags)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `["verão","promoção"]`, string(data))
+}
+
+func TestProductTagsValidation(t *testing.T) {
+	_, err := NewProduct("product", entity.MustParseMoney("10", "BRL"), WithTags("a,b"))
+	assert.ErrorIs(t, err, ErrInvalidTag)
+
+	names := make([]string, MaxProductTags+1)
+	for i := range names {
+		names[i] = strings.Repeat("a", i+1)
+	}
+	_, err = NewProduct("product", entity.MustParseMoney("10", "BRL"), WithTags(names...))
+	assert.ErrorIs(t, err, ErrTooManyTags)
+
+	product, err := NewProduct("product", entity.MustParseMoney("10", "BRL"), WithTags(names[:MaxProductTags]...))
+	assert.NoError(t, err)
+	assert.Len(t, product.Tags, MaxProductTags)
+}
@@ -1,18 +1,43 @@
 package entity
 
 import (
+	"errors"
+	"strings"
+	"sync"
+
 	"github.com/waanvieira/api-users/pkg/entity"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Tamanho mínimo da senha em caracteres
+const MinPasswordLength = 6
+
+var (
+	ErrEmailIsRequired        = errors.New("Email is required")
+	ErrPasswordIsRequired     = errors.New("Password is required")
+	ErrPasswordTooShort       = errors.New("password is too short")
+	ErrEmailAlreadyRegistered = errors.New("email already registered")
+	ErrInvalidCredentials     = errors.New("invalid email or password")
+)
+
 type User struct {
-	ID       entity.ID `json:"id"`
-	Name     string    `json:"name"`
-	Email    string    `json:"email"`
-	Password string    `json:"-"`
+	ID   entity.ID `json:"id"`
+	Name string    `json:"name"`
+	// Sempre normalizado (minúsculo e sem espaços), o índice único garante um usuário por email
+	Email    string `json:"email" gorm:"size:255;uniqueIndex:idx_users_email"`
+	Password string `json:"-"`
+	// Perfil usado para liberar as rotas, todo usuário novo começa como viewer
+	Role Role `json:"role" gorm:"size:20;not null;default:viewer"`
 }
 
 func NewUser(name, email string, password string) (*User, error) {
+	var errs ValidationErrors
+	address := validateProfile(&errs, name, email)
+	validatePassword(&errs, password)
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return nil, err
@@ -21,12 +46,85 @@ func NewUser(name, email string, password string) (*User, error) {
 	return &User{
 		ID:       entity.NewID(),
 		Name:     name,
-		Email:    entity.NewEmailAddress(email).String(),
+		Email:    address.String(),
 		Password: string(hash),
+		Role:     RoleViewer,
 	}, nil
 }
 
+// Update altera nome, email e, quando informada, a senha; se alguma regra falhar nada é alterado
+func (u *User) Update(name, email, password string) error {
+	var errs ValidationErrors
+	address := validateProfile(&errs, name, email)
+	if password != "" {
+		validatePassword(&errs, password)
+	}
+	if err := errs.Err(); err != nil {
+		return err
+	}
+
+	if password != "" {
+		if err := u.ChangePassword(password); err != nil {
+			return err
+		}
+	}
+	u.Name = name
+	u.Email = address.String()
+	return nil
+}
+
+// ChangePassword troca a senha guardando apenas o hash, igual ao NewUser
+func (u *User) ChangePassword(password string) error {
+	var errs ValidationErrors
+	validatePassword(&errs, password)
+	if err := errs.Err(); err != nil {
+		return err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	u.Password = string(hash)
+	return nil
+}
+
 func (u *User) ValidatePassword(password string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
 	return err == nil
 }
+
+// dummyPasswordHash é gerado uma única vez, com o mesmo custo das senhas dos usuários
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+	return hash
+})
+
+// CompareDummyPassword faz a mesma comparação do ValidatePassword contra um hash fixo
+// Usado no login quando o email não existe, assim a resposta leva o mesmo tempo da senha errada
+func CompareDummyPassword(password string) {
+	bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
+}
+
+// validateProfile valida nome e email e devolve o email já normalizado
+func validateProfile(errs *ValidationErrors, name, email string) entity.EmailAddress {
+	if name == "" {
+		errs.Add("name", CodeRequired, ErrNameIsRequired)
+	}
+	if strings.TrimSpace(email) == "" {
+		errs.Add("email", CodeRequired, ErrEmailIsRequired)
+		return entity.EmailAddress{}
+	}
+	address, err := entity.NewEmailAddress(email)
+	if err != nil {
+		errs.Add("email", CodeInvalid, err)
+	}
+	return address
+}
+
+func validatePassword(errs *ValidationErrors, password string) {
+	if password == "" {
+		errs.Add("password", CodeRequired, ErrPasswordIsRequired)
+	} else if len([]rune(password)) < MinPasswordLength {
+		errs.Add("password", CodeTooShort, ErrPasswordTooShort)
+	}
+}
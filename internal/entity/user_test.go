@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/waanvieira/api-users/pkg/entity"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestNewUser(t *testing.T) {
@@ -16,6 +18,7 @@ func TestNewUser(t *testing.T) {
 	assert.NotEmpty(t, user.Password)
 	assert.Equal(t, "test", user.Name)
 	assert.Equal(t, "teste@dev.com", user.Email)
+	assert.Equal(t, RoleViewer, user.Role)
 }
 
 func TestUser_ValidatePassword(t *testing.T) {
@@ -25,3 +28,54 @@ func TestUser_ValidatePassword(t *testing.T) {
 	assert.False(t, user.ValidatePassword("1234567"))
 	assert.NotEqual(t, "123456", user.Password)
 }
+
+func TestUser_ChangePassword(t *testing.T) {
+	user, err := NewUser("test", "teste@dev.com", "123456")
+	assert.Nil(t, err)
+	assert.Nil(t, user.ChangePassword("654321"))
+	assert.ErrorIs(t, user.ChangePassword("1"), ErrPasswordTooShort)
+	assert.True(t, user.ValidatePassword("654321"))
+	assert.False(t, user.ValidatePassword("123456"))
+	assert.NotEqual(t, "654321", user.Password)
+}
+
+func TestNewUserValidatesEveryField(t *testing.T) {
+	user, err := NewUser("", "not-an-email", "123")
+	assert.Nil(t, user)
+
+	var errs ValidationErrors
+	assert.ErrorAs(t, err, &errs)
+	assert.Equal(t, ValidationErrors{
+		{Field: "name", Code: CodeRequired, Err: ErrNameIsRequired},
+		{Field: "email", Code: CodeInvalid, Err: entity.ErrInvalidEmailAddress},
+		{Field: "password", Code: CodeTooShort, Err: ErrPasswordTooShort},
+	}, errs)
+
+	_, err = NewUser("test", "", "")
+	assert.ErrorIs(t, err, ErrEmailIsRequired)
+	assert.ErrorIs(t, err, ErrPasswordIsRequired)
+}
+
+func TestUser_Update(t *testing.T) {
+	user, _ := NewUser("test", "teste@dev.com", "123456")
+
+	// Senha vazia mantém a atual
+	assert.Nil(t, user.Update("new name", "new@dev.com", ""))
+	assert.Equal(t, "new name", user.Name)
+	assert.Equal(t, "new@dev.com", user.Email)
+	assert.True(t, user.ValidatePassword("123456"))
+
+	// Com erro nada é alterado
+	err := user.Update("", "new@dev.com", "1")
+	assert.ErrorIs(t, err, ErrNameIsRequired)
+	assert.ErrorIs(t, err, ErrPasswordTooShort)
+	assert.Equal(t, "new name", user.Name)
+	assert.True(t, user.ValidatePassword("123456"))
+}
+
+func TestCompareDummyPasswordUsesTheSameCost(t *testing.T) {
+	cost, err := bcrypt.Cost(dummyPasswordHash())
+	assert.Nil(t, err)
+	assert.Equal(t, bcrypt.DefaultCost, cost)
+	CompareDummyPassword("qualquer")
+}
@@ -0,0 +1,57 @@
+package entity
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrImmutableField = errors.New("field cannot be changed")
+
+// Códigos estáveis das regras de validação, usados pelo frontend para montar as mensagens
+const (
+	CodeRequired = "required"
+	CodeInvalid  = "invalid"
+	CodeTooShort = "too_short"
+	CodeTooLong  = "too_long"
+	// Campo que não pode ser alterado depois do cadastro, ex: id
+	CodeImmutable = "immutable"
+)
+
+// FieldError é uma regra de validação que falhou em um campo
+type FieldError struct {
+	Field string `json:"field" example:"price"`
+	Code  string `json:"code" example:"invalid"`
+	// Erro do domínio que originou a falha, permite usar errors.Is(err, ErrInvalidPrice)
+	Err error `json:"-"`
+}
+
+// ValidationErrors junta todas as regras que falharam, assim o cliente corrige tudo de uma vez
+type ValidationErrors []FieldError
+
+func (v *ValidationErrors) Add(field, code string, err error) {
+	*v = append(*v, FieldError{Field: field, Code: code, Err: err})
+}
+
+// Err devolve nil quando nenhuma regra falhou, evita devolver um ValidationErrors vazio como erro
+func (v ValidationErrors) Err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return v
+}
+
+func (v ValidationErrors) Error() string {
+	messages := make([]string, 0, len(v))
+	for _, fe := range v {
+		messages = append(messages, fe.Field+": "+fe.Err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (v ValidationErrors) Unwrap() []error {
+	errs := make([]error, 0, len(v))
+	for _, fe := range v {
+		errs = append(errs, fe.Err)
+	}
+	return errs
+}
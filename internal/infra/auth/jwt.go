@@ -0,0 +1,339 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+var (
+	ErrUnsupportedAlgorithm = errors.New("unsupported jwt algorithm")
+	ErrUnknownKeyID         = errors.New("unknown jwt key id")
+	ErrInvalidKey           = errors.New("invalid jwt key")
+)
+
+// Config diz como os tokens são assinados
+// No HS256 usamos o Secret, no RS256/ES256 a chave privada em PEM e as chaves públicas ficam disponíveis no JWKS
+type Config struct {
+	Algorithm      string
+	Secret         string
+	PrivateKeyFile string
+	KeyID          string
+	// Chaves públicas antigas que ainda aceitamos durante a rotação, no formato kid=caminho.pem
+	VerifyKeyFiles []string
+}
+
+type verificationKey struct {
+	alg jwa.SignatureAlgorithm
+	raw interface{}
+}
+
+// JWTAuth substitui o jwtauth.JWTAuth: assina com uma chave (com kid no header) e valida com várias
+// O token é validado pela chave do kid, assim podemos trocar a chave de assinatura sem derrubar os tokens já emitidos
+type JWTAuth struct {
+	alg        jwa.SignatureAlgorithm
+	signKey    jwk.Key
+	verifyKeys map[string]verificationKey
+	publicKeys jwk.Set
+}
+
+// New monta o JWTAuth a partir da configuração, lendo as chaves PEM quando o algoritmo é assimétrico
+func New(cfg Config) (*JWTAuth, error) {
+	alg := jwa.SignatureAlgorithm(strings.ToUpper(cfg.Algorithm))
+	if alg == "" {
+		alg = jwa.HS256
+	}
+
+	var rawSignKey interface{}
+	switch alg {
+	case jwa.HS256:
+		if cfg.Secret == "" {
+			return nil, fmt.Errorf("%w: HS256 requires JWT_SECRET", ErrInvalidKey)
+		}
+		rawSignKey = []byte(cfg.Secret)
+	case jwa.RS256, jwa.ES256:
+		if cfg.PrivateKeyFile == "" {
+			return nil, fmt.Errorf("%w: %s requires JWT_PRIVATE_KEY_FILE", ErrInvalidKey, alg)
+		}
+		key, err := readPrivateKey(cfg.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkKeyMatchesAlgorithm(alg, key); err != nil {
+			return nil, err
+		}
+		rawSignKey = key
+	default:
+		return nil, fmt.Errorf("%w %q (use HS256, RS256 or ES256)", ErrUnsupportedAlgorithm, cfg.Algorithm)
+	}
+
+	signKey, err := newKey(rawSignKey, alg, cfg.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &JWTAuth{
+		alg:        alg,
+		signKey:    signKey,
+		verifyKeys: map[string]verificationKey{},
+		publicKeys: jwk.NewSet(),
+	}
+
+	if alg == jwa.HS256 {
+		// O segredo nunca vai para o JWKS
+		a.verifyKeys[signKey.KeyID()] = verificationKey{alg: alg, raw: rawSignKey}
+	} else if err := a.addPublicKey(signKey.KeyID(), alg, publicKeyOf(rawSignKey)); err != nil {
+		return nil, err
+	}
+
+	for _, entry := range cfg.VerifyKeyFiles {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kid, path, found := strings.Cut(entry, "=")
+		if !found || kid == "" || path == "" {
+			return nil, fmt.Errorf("%w: verify key %q must be kid=path.pem", ErrInvalidKey, entry)
+		}
+		key, err := readPublicKey(path)
+		if err != nil {
+			return nil, err
+		}
+		keyAlg := algorithmOf(key)
+		if keyAlg == "" {
+			return nil, fmt.Errorf("%w: %s must be an RSA or P-256 public key", ErrInvalidKey, path)
+		}
+		if _, exists := a.verifyKeys[kid]; exists {
+			return nil, fmt.Errorf("%w: duplicated key id %q", ErrInvalidKey, kid)
+		}
+		if err := a.addPublicKey(kid, keyAlg, key); err != nil {
+			return nil, err
+		}
+	}
+
+	return a, nil
+}
+
+func (a *JWTAuth) addPublicKey(kid string, alg jwa.SignatureAlgorithm, raw interface{}) error {
+	key, err := newKey(raw, alg, kid)
+	if err != nil {
+		return err
+	}
+	a.verifyKeys[key.KeyID()] = verificationKey{alg: alg, raw: raw}
+	a.publicKeys.Add(key)
+	return nil
+}
+
+// newKey cria a jwk com alg, use e kid, se o kid não for informado usamos o thumbprint da chave (RFC 7638)
+func newKey(raw interface{}, alg jwa.SignatureAlgorithm, kid string) (jwk.Key, error) {
+	key, err := jwk.New(raw)
+	if err != nil {
+		return nil, err
+	}
+	if err := key.Set(jwk.AlgorithmKey, alg); err != nil {
+		return nil, err
+	}
+	if err := key.Set(jwk.KeyUsageKey, "sig"); err != nil {
+		return nil, err
+	}
+	if kid != "" {
+		err = key.Set(jwk.KeyIDKey, kid)
+	} else {
+		err = jwk.AssignKeyID(key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// KeyID é o kid colocado no header dos tokens emitidos
+func (a *JWTAuth) KeyID() string {
+	return a.signKey.KeyID()
+}
+
+// Encode tem a mesma assinatura do jwtauth.JWTAuth.Encode, o kid vai no header do token
+func (a *JWTAuth) Encode(claims map[string]interface{}) (jwt.Token, string, error) {
+	t := jwt.New()
+	for k, v := range claims {
+		if err := t.Set(k, v); err != nil {
+			return nil, "", err
+		}
+	}
+	payload, err := jwt.Sign(t, a.alg, a.signKey)
+	if err != nil {
+		return nil, "", err
+	}
+	return t, string(payload), nil
+}
+
+// Decode valida a assinatura usando a chave do kid do header
+// O algoritmo do header precisa ser o mesmo da chave, assim ninguém consegue trocar RS256 por HS256 no token
+func (a *JWTAuth) Decode(tokenString string) (jwt.Token, error) {
+	msg, err := jws.Parse([]byte(tokenString))
+	if err != nil {
+		return nil, err
+	}
+	if len(msg.Signatures()) != 1 {
+		return nil, jwtauth.ErrUnauthorized
+	}
+	headers := msg.Signatures()[0].ProtectedHeaders()
+
+	kid := headers.KeyID()
+	// Tokens emitidos antes de termos o kid foram assinados com a chave atual
+	if kid == "" {
+		kid = a.KeyID()
+	}
+	key, ok := a.verifyKeys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKeyID, kid)
+	}
+	if headers.Algorithm() != key.alg {
+		return nil, jwtauth.ErrAlgoInvalid
+	}
+	return jwt.Parse([]byte(tokenString), jwt.WithVerify(key.alg, key.raw))
+}
+
+// Verifier faz o mesmo que o jwtauth.Verifier, porém usando as nossas chaves
+// O token e o erro ficam no contexto do jwtauth, então o jwtauth.Authenticator e o jwtauth.FromContext continuam funcionando
+func Verifier(a *JWTAuth) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, err := a.verifyRequest(r)
+			ctx := jwtauth.NewContext(r.Context(), token, err)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func (a *JWTAuth) verifyRequest(r *http.Request) (jwt.Token, error) {
+	tokenString := jwtauth.TokenFromHeader(r)
+	if tokenString == "" {
+		tokenString = jwtauth.TokenFromCookie(r)
+	}
+	if tokenString == "" {
+		return nil, jwtauth.ErrNoTokenFound
+	}
+	token, err := a.Decode(tokenString)
+	if err != nil {
+		return token, jwtauth.ErrorReason(err)
+	}
+	if err := jwt.Validate(token); err != nil {
+		return token, jwtauth.ErrorReason(err)
+	}
+	return token, nil
+}
+
+// PublicKeys retorna as chaves públicas (assinatura atual + rotação), vazio no HS256
+func (a *JWTAuth) PublicKeys() jwk.Set {
+	return a.publicKeys
+}
+
+// JWKS godoc
+// @Summary      JSON Web Key Set
+// @Description  Public keys used to verify the issued JWTs, selected by the kid header. Empty when tokens are signed with HS256
+// @Tags         auth
+// @Produce      json
+// @Success      200
+// @Router       /.well-known/jwks.json [get]
+// JWKS é o handler do /.well-known/jwks.json, os outros serviços usam essas chaves para validar os nossos tokens
+func (a *JWTAuth) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	// O jwk.Set já serializa no formato {"keys": [...]}
+	json.NewEncoder(w).Encode(a.publicKeys)
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: %s is not a PEM file", ErrInvalidKey, path)
+	}
+	return block, nil
+}
+
+// readPrivateKey aceita PKCS#8 ("PRIVATE KEY"), PKCS#1 ("RSA PRIVATE KEY") e SEC 1 ("EC PRIVATE KEY")
+func readPrivateKey(path string) (interface{}, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	return nil, fmt.Errorf("%w: unsupported PEM type %q in %s", ErrInvalidKey, block.Type, path)
+}
+
+// readPublicKey aceita PKIX ("PUBLIC KEY") e PKCS#1 ("RSA PUBLIC KEY")
+func readPublicKey(path string) (interface{}, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+	return nil, fmt.Errorf("%w: unsupported PEM type %q in %s", ErrInvalidKey, block.Type, path)
+}
+
+func publicKeyOf(private interface{}) interface{} {
+	switch key := private.(type) {
+	case *rsa.PrivateKey:
+		return &key.PublicKey
+	case *ecdsa.PrivateKey:
+		return &key.PublicKey
+	}
+	return nil
+}
+
+func algorithmOf(key interface{}) jwa.SignatureAlgorithm {
+	switch key := key.(type) {
+	case *rsa.PublicKey, *rsa.PrivateKey:
+		return jwa.RS256
+	case *ecdsa.PublicKey:
+		if key.Curve == elliptic.P256() {
+			return jwa.ES256
+		}
+	case *ecdsa.PrivateKey:
+		if key.Curve == elliptic.P256() {
+			return jwa.ES256
+		}
+	}
+	return ""
+}
+
+func checkKeyMatchesAlgorithm(alg jwa.SignatureAlgorithm, key interface{}) error {
+	if algorithmOf(key) != alg {
+		return fmt.Errorf("%w: private key does not match %s (RS256 needs RSA, ES256 needs EC P-256)", ErrInvalidKey, alg)
+	}
+	if rsaKey, ok := key.(*rsa.PrivateKey); ok && rsaKey.N.BitLen() < 2048 {
+		return fmt.Errorf("%w: RSA keys must have at least 2048 bits", ErrInvalidKey)
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeKeys gera um par de chaves e salva em PEM, devolvendo o caminho da privada e da pública
+func writeKeys(t *testing.T, private interface{}, public interface{}) (string, string) {
+	dir := t.TempDir()
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	assert.NoError(t, err)
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	assert.NoError(t, err)
+
+	privatePath := filepath.Join(dir, "private.pem")
+	publicPath := filepath.Join(dir, "public.pem")
+	assert.NoError(t, os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0600))
+	assert.NoError(t, os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0644))
+	return privatePath, publicPath
+}
+
+func rsaKeys(t *testing.T) (string, string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	return writeKeys(t, key, &key.PublicKey)
+}
+
+func ecKeys(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	return writeKeys(t, key, &key.PublicKey)
+}
+
+func claims() map[string]interface{} {
+	return map[string]interface{}{
+		"sub": "user-id",
+		"exp": time.Now().Add(time.Minute).Unix(),
+	}
+}
+
+func headerKeyID(t *testing.T, tokenString string) string {
+	msg, err := jws.Parse([]byte(tokenString))
+	assert.NoError(t, err)
+	return msg.Signatures()[0].ProtectedHeaders().KeyID()
+}
+
+func TestHS256(t *testing.T) {
+	a, err := New(Config{Secret: "secret", KeyID: "hs-1"})
+	assert.NoError(t, err)
+
+	_, tokenString, err := a.Encode(claims())
+	assert.NoError(t, err)
+	assert.Equal(t, "hs-1", headerKeyID(t, tokenString))
+
+	token, err := a.Decode(tokenString)
+	assert.NoError(t, err)
+	assert.Equal(t, "user-id", token.Subject())
+	// O segredo nunca é publicado
+	assert.Equal(t, 0, a.PublicKeys().Len())
+
+	// Tokens antigos, sem kid, ainda são aceitos com a chave atual
+	legacy := jwtauth.New("HS256", []byte("secret"), nil)
+	_, legacyString, _ := legacy.Encode(claims())
+	_, err = a.Decode(legacyString)
+	assert.NoError(t, err)
+
+	_, err = New(Config{Algorithm: "HS256"})
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestRS256AndES256(t *testing.T) {
+	for alg, keys := range map[string]func(*testing.T) (string, string){"RS256": rsaKeys, "ES256": ecKeys} {
+		privatePath, _ := keys(t)
+		a, err := New(Config{Algorithm: alg, PrivateKeyFile: privatePath})
+		assert.NoError(t, err, alg)
+
+		_, tokenString, err := a.Encode(claims())
+		assert.NoError(t, err)
+		// Sem JWT_KEY_ID o kid é o thumbprint da chave
+		assert.NotEmpty(t, a.KeyID())
+		assert.Equal(t, a.KeyID(), headerKeyID(t, tokenString))
+
+		token, err := a.Decode(tokenString)
+		assert.NoError(t, err)
+		assert.Equal(t, "user-id", token.Subject())
+		assert.Equal(t, 1, a.PublicKeys().Len())
+	}
+}
+
+func TestPrivateKeyMustMatchAlgorithm(t *testing.T) {
+	privatePath, _ := ecKeys(t)
+	_, err := New(Config{Algorithm: "RS256", PrivateKeyFile: privatePath})
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	_, err = New(Config{Algorithm: "none"})
+	assert.ErrorIs(t, err, ErrUnsupportedAlgorithm)
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldPrivate, oldPublic := rsaKeys(t)
+	oldAuth, err := New(Config{Algorithm: "RS256", PrivateKeyFile: oldPrivate, KeyID: "2026-01"})
+	assert.NoError(t, err)
+	_, oldToken, err := oldAuth.Encode(claims())
+	assert.NoError(t, err)
+
+	// A chave nova assina e a antiga continua validando os tokens que já foram emitidos
+	newPrivate, _ := ecKeys(t)
+	a, err := New(Config{
+		Algorithm:      "ES256",
+		PrivateKeyFile: newPrivate,
+		KeyID:          "2026-02",
+		VerifyKeyFiles: []string{"2026-01=" + oldPublic, ""},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, a.PublicKeys().Len())
+
+	_, err = a.Decode(oldToken)
+	assert.NoError(t, err)
+	_, newToken, err := a.Encode(claims())
+	assert.NoError(t, err)
+	assert.Equal(t, "2026-02", headerKeyID(t, newToken))
+
+	// Depois que a chave antiga sai da configuração os tokens dela deixam de valer
+	withoutOld, err := New(Config{Algorithm: "ES256", PrivateKeyFile: newPrivate, KeyID: "2026-02"})
+	assert.NoError(t, err)
+	_, err = withoutOld.Decode(oldToken)
+	assert.ErrorIs(t, err, ErrUnknownKeyID)
+
+	_, err = New(Config{Algorithm: "ES256", PrivateKeyFile: newPrivate, VerifyKeyFiles: []string{oldPublic}})
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestDecodeRejectsAlgorithmMismatch(t *testing.T) {
+	privatePath, publicPath := rsaKeys(t)
+	a, err := New(Config{Algorithm: "RS256", PrivateKeyFile: privatePath, KeyID: "rsa"})
+	assert.NoError(t, err)
+
+	// Token HS256 assinado com a chave pública usando o mesmo kid
+	publicPEM, _ := os.ReadFile(publicPath)
+	forged, err := New(Config{Secret: string(publicPEM), KeyID: "rsa"})
+	assert.NoError(t, err)
+	_, forgedToken, _ := forged.Encode(claims())
+
+	_, err = a.Decode(forgedToken)
+	assert.ErrorIs(t, err, jwtauth.ErrAlgoInvalid)
+}
+
+func TestVerifierAndJWKS(t *testing.T) {
+	privatePath, _ := rsaKeys(t)
+	a, err := New(Config{Algorithm: "RS256", PrivateKeyFile: privatePath, KeyID: "rsa"})
+	assert.NoError(t, err)
+	_, tokenString, _ := a.Encode(claims())
+
+	handler := Verifier(a)(jwtauth.Authenticator(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, claims, _ := jwtauth.FromContext(r.Context())
+		w.Write([]byte(claims["sub"].(string)))
+	})))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+tokenString)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "user-id", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = httptest.NewRecorder()
+	a.JWKS(rec, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var body struct {
+		Keys []map[string]interface{} `json:"keys"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Len(t, body.Keys, 1)
+	assert.Equal(t, "rsa", body.Keys[0]["kid"])
+	assert.Equal(t, "RS256", body.Keys[0]["alg"])
+	assert.Equal(t, "sig", body.Keys[0]["use"])
+	// Apenas a parte pública da chave
+	assert.NotContains(t, body.Keys[0], "d")
+	assert.False(t, strings.Contains(rec.Body.String(), "PRIVATE"))
+}
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/waanvieira/api-users/internal/entity"
+	"gorm.io/gorm"
+)
+
+type Category struct {
+	DB *gorm.DB
+}
+
+func NewCategory(db *gorm.DB) *Category {
+	return &Category{DB: db}
+}
+
+// Create grava a categoria depois de verificar que o pai existe
+func (c *Category) Create(category *entity.Category) error {
+	return c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := checkParent(tx, category); err != nil {
+			return err
+		}
+		return tx.Create(category).Error
+	})
+}
+
+func (c *Category) FindByID(id string) (*entity.Category, error) {
+	var category entity.Category
+	if err := c.DB.Where("id = ?", id).First(&category).Error; err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// FindByIDs busca as categorias informadas, ids repetidos são ignorados
+// Se alguma não existir retorna entity.ErrCategoryNotFound
+func (c *Category) FindByIDs(ids []string) ([]entity.Category, error) {
+	unique := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		unique[id] = true
+	}
+	if len(unique) == 0 {
+		return []entity.Category{}, nil
+	}
+	var categories []entity.Category
+	if err := c.DB.Where("id IN ?", ids).Order("name").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	if len(categories) != len(unique) {
+		return nil, entity.ErrCategoryNotFound
+	}
+	return categories, nil
+}
+
+// FindAll devolve todas as categorias em ordem alfabética, a árvore é montada com entity.BuildCategoryTree
+func (c *Category) FindAll() ([]entity.Category, error) {
+	var categories []entity.Category
+	err := c.DB.Order("name").Order("id").Find(&categories).Error
+	return categories, err
+}
+
+// Update grava nome e pai, recusando mover a categoria para baixo dela mesma
+func (c *Category) Update(category *entity.Category) error {
+	return c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := checkParent(tx, category); err != nil {
+			return err
+		}
+		category.UpdatedAt = time.Now()
+		result := tx.Model(category).Select("name", "parent_id", "updated_at").Updates(category)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// Delete apaga a categoria e a ligação com os produtos, os produtos continuam no catálogo
+// Categorias com subcategorias não são apagadas para não deixar filhos sem pai
+func (c *Category) Delete(id string) error {
+	return c.DB.Transaction(func(tx *gorm.DB) error {
+		var children int64
+		if err := tx.Model(&entity.Category{}).Where("parent_id = ?", id).Count(&children).Error; err != nil {
+			return err
+		}
+		if children > 0 {
+			return entity.ErrCategoryHasChildren
+		}
+		if err := tx.Exec("DELETE FROM product_categories WHERE category_id = ?", id).Error; err != nil {
+			return err
+		}
+		result := tx.Where("id = ?", id).Delete(&entity.Category{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// checkParent sobe a partir do novo pai até a raiz, se passar pela própria categoria a mudança criaria um ciclo
+func checkParent(tx *gorm.DB, category *entity.Category) error {
+	visited := map[string]bool{}
+	for parentID := category.ParentID; parentID != nil; {
+		if *parentID == category.ID {
+			return entity.ErrCategoryCycle
+		}
+		// Proteção para um ciclo que já exista no banco não travar a requisição
+		if visited[parentID.String()] {
+			return entity.ErrCategoryCycle
+		}
+		visited[parentID.String()] = true
+
+		var parent entity.Category
+		err := tx.Select("id", "parent_id").Where("id = ?", parentID.String()).First(&parent).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return entity.ErrCategoryParentNotFound
+		}
+		if err != nil {
+			return err
+		}
+		parentID = parent.ParentID
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/waanvieira/api-users/internal/entity"
+	entityPkg "github.com/waanvieira/api-users/pkg/entity"
+
+	// Banco em memória sqlite
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// O Product cria também a tabela de categorias e a product_categories
+	db.AutoMigrate(&entity.Product{}, &entity.Category{})
+	return db
+}
+
+func TestCreateCategory(t *testing.T) {
+	categoryDB := NewCategory(newTestDB(t))
+
+	root, _ := entity.NewCategory("Roupas", nil)
+	assert.NoError(t, categoryDB.Create(root))
+	child, _ := entity.NewCategory("Camisetas", &root.ID)
+	assert.NoError(t, categoryDB.Create(child))
+
+	found, err := categoryDB.FindByID(child.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, root.ID, *found.ParentID)
+
+	missing := entityPkg.NewID()
+	orphan, _ := entity.NewCategory("Sem pai", &missing)
+	assert.ErrorIs(t, categoryDB.Create(orphan), entity.ErrCategoryParentNotFound)
+}
+
+func TestUpdateCategoryPreventsCycles(t *testing.T) {
+	categoryDB := NewCategory(newTestDB(t))
+
+	a, _ := entity.NewCategory("A", nil)
+	assert.NoError(t, categoryDB.Create(a))
+	b, _ := entity.NewCategory("B", &a.ID)
+	assert.NoError(t, categoryDB.Create(b))
+	c, _ := entity.NewCategory("C", &b.ID)
+	assert.NoError(t, categoryDB.Create(c))
+
+	// A -> B -> C, mover A para baixo de C fecharia um ciclo
+	a.ParentID = &c.ID
+	assert.ErrorIs(t, categoryDB.Update(a), entity.ErrCategoryCycle)
+	a.ParentID = &a.ID
+	assert.ErrorIs(t, categoryDB.Update(a), entity.ErrCategoryCycle)
+
+	// Mover C para a raiz e depois A para baixo de C é permitido
+	c.ParentID = nil
+	assert.NoError(t, categoryDB.Update(c))
+	a.ParentID = &c.ID
+	assert.NoError(t, categoryDB.Update(a))
+
+	found, err := categoryDB.FindByID(a.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, c.ID, *found.ParentID)
+	found, err = categoryDB.FindByID(c.ID.String())
+	assert.NoError(t, err)
+	assert.Nil(t, found.ParentID)
+}
+
+func TestFindCategoriesByIDs(t *testing.T) {
+	categoryDB := NewCategory(newTestDB(t))
+
+	a, _ := entity.NewCategory("A", nil)
+	b, _ := entity.NewCategory("B", nil)
+	assert.NoError(t, categoryDB.Create(a))
+	assert.NoError(t, categoryDB.Create(b))
+
+	categories, err := categoryDB.FindByIDs([]string{b.ID.String(), a.ID.String(), a.ID.String()})
+	assert.NoError(t, err)
+	assert.Len(t, categories, 2)
+
+	_, err = categoryDB.FindByIDs([]string{a.ID.String(), entityPkg.NewID().String()})
+	assert.ErrorIs(t, err, entity.ErrCategoryNotFound)
+
+	categories, err = categoryDB.FindByIDs(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, categories)
+}
+
+func TestDeleteCategory(t *testing.T) {
+	db := newTestDB(t)
+	categoryDB := NewCategory(db)
+
+	root, _ := entity.NewCategory("Roupas", nil)
+	child, _ := entity.NewCategory("Camisetas", &root.ID)
+	assert.NoError(t, categoryDB.Create(root))
+	assert.NoError(t, categoryDB.Create(child))
+	product, _ := entity.NewProduct("Camiseta", entityPkg.MustParseMoney("10", "BRL"), entity.WithCategories(*child))
+	assert.NoError(t, db.Omit("Categories.*").Create(product).Error)
+
+	assert.ErrorIs(t, categoryDB.Delete(root.ID.String()), entity.ErrCategoryHasChildren)
+	assert.NoError(t, categoryDB.Delete(child.ID.String()))
+	assert.NoError(t, categoryDB.Delete(root.ID.String()))
+	assert.ErrorIs(t, categoryDB.Delete(root.ID.String()), gorm.ErrRecordNotFound)
+
+	// O produto continua, apenas sem a categoria
+	var found entity.Product
+	assert.NoError(t, db.Preload("Categories").First(&found, "id = ?", product.ID).Error)
+	assert.Empty(t, found.Categories)
+}
@@ -0,0 +1,67 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/waanvieira/api-users/internal/entity"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestOpenSQLiteInMemory(t *testing.T) {
+	db, err := Open(Config{Driver: "sqlite", Name: ":memory:"})
+	assert.NoError(t, err)
+	assert.NotNil(t, db)
+
+	// Garantindo que conseguimos usar o banco aberto pelo registro de drivers
+	assert.NoError(t, db.AutoMigrate(&entity.User{}))
+	user, _ := entity.NewUser("user test", "user@teste.com", "123456")
+	assert.NoError(t, NewUser(db).Create(user))
+	// Erros do banco chegam traduzidos para os erros do gorm
+	assert.ErrorIs(t, db.Create(user).Error, gorm.ErrDuplicatedKey)
+
+	sqlDB, err := db.DB()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sqlDB.Stats().MaxOpenConnections)
+}
+
+func TestOpenUnknownDriver(t *testing.T) {
+	db, err := Open(Config{Driver: "oracle"})
+	assert.Nil(t, db)
+	assert.ErrorIs(t, err, ErrUnknownDriver)
+	assert.Contains(t, err.Error(), "oracle")
+	assert.Contains(t, err.Error(), "mysql")
+}
+
+func TestMySQLDSN(t *testing.T) {
+	dsn, err := MySQLDSN(Config{Host: "db", User: "root", Password: "p@ss", Name: "users"})
+	assert.NoError(t, err)
+	assert.Contains(t, dsn, "root:p@ss@tcp(db:3306)/users?")
+	assert.Contains(t, dsn, "parseTime=true")
+	assert.Contains(t, dsn, "multiStatements=true")
+
+	_, err = MySQLDSN(Config{User: "root"})
+	assert.Error(t, err)
+}
+
+func TestPostgresDSN(t *testing.T) {
+	dsn, err := PostgresDSN(Config{Host: "db", Port: "6543", User: "app", Password: "a b", Name: "users"})
+	assert.NoError(t, err)
+	assert.Equal(t, "postgres://app:a%20b@db:6543/users?sslmode=disable", dsn)
+}
+
+func TestRegisterDriver(t *testing.T) {
+	RegisterDriver("memory", func(cfg Config) (gorm.Dialector, error) {
+		return sqlite.Open("file::memory:"), nil
+	})
+	defer func() {
+		driversMu.Lock()
+		delete(drivers, "memory")
+		driversMu.Unlock()
+	}()
+
+	db, err := Open(Config{Driver: "MEMORY"})
+	assert.NoError(t, err)
+	assert.NotNil(t, db)
+}
@@ -1,16 +1,76 @@
 package database
 
-import "github.com/waanvieira/api-users/internal/entity"
+import (
+	"time"
+
+	"github.com/waanvieira/api-users/internal/entity"
+	"github.com/waanvieira/api-users/pkg/filter"
+)
 
 type UserInterface interface {
 	Create(user *entity.User) error
 	FindByEmail(email string) (*entity.User, error)
+	FindByID(id string) (*entity.User, error)
+	FindAll(page, limit int, sort string) ([]entity.User, error)
+	Update(user *entity.User) error
+	Delete(id string) error
+}
+
+// ProductFilter restringe a listagem de produtos, campos vazios não filtram nada
+type ProductFilter struct {
+	OwnerID string
+	// Produtos ligados à categoria e, com IncludeSubcategories, a qualquer subcategoria dela
+	CategoryID           string
+	IncludeSubcategories bool
+	// Tags normalizadas; com MatchAllTags o produto precisa ter todas, senão basta uma
+	Tags         []string
+	MatchAllTags bool
+	// Expressão do ?filter=, os campos aceitos ficam no repositório
+	Expression filter.Node
 }
 
 type ProductInterface interface {
 	Create(product *entity.Product) error
 	FindByID(email string) (*entity.Product, error)
-	FindAll(page, limit int, sort string) ([]entity.Product, error)
+	// Sem ordenação informada ordena por created_at, sempre desempatando pelo id
+	FindAll(page, limit int, sort []SortField, filter ProductFilter) ([]entity.Product, error)
+	// FindPage pagina por cursor (keyset): a página seguinte começa depois do último produto, não em um offset
+	FindPage(sort []SortField, page CursorPage, filter ProductFilter) ([]entity.Product, PageInfo, error)
+	Count(filter ProductFilter) (int64, error)
+	// Search busca os termos no nome e na descrição, do mais para o menos relevante, e devolve também o total encontrado
+	Search(terms []SearchTerm, page, limit int) ([]entity.ProductSearchResult, int64, error)
 	Update(product *entity.Product) error
+	// Delete só remove o produto que ainda está na versão informada, senão devolve entity.ErrVersionConflict
+	Delete(id string, version int64) error
+	FindDeleted(page, limit int, filter ProductFilter) ([]entity.Product, error)
+	FindDeletedByID(id string) (*entity.Product, error)
+	Restore(id string, version int64) (*entity.Product, error)
+	Purge(id string) error
+	SetCategories(product *entity.Product, categories []entity.Category) error
+}
+
+type TagInterface interface {
+	FindAllWithCount() ([]entity.TagCount, error)
+}
+
+type CategoryInterface interface {
+	Create(category *entity.Category) error
+	FindByID(id string) (*entity.Category, error)
+	FindByIDs(ids []string) ([]entity.Category, error)
+	FindAll() ([]entity.Category, error)
+	Update(category *entity.Category) error
 	Delete(id string) error
 }
+
+type RefreshTokenInterface interface {
+	Create(token *entity.RefreshToken) error
+	FindByHash(hash string) (*entity.RefreshToken, error)
+	Rotate(current *entity.RefreshToken, next *entity.RefreshToken) error
+	RevokeFamily(familyID string) error
+	RevokeUser(userID string) error
+}
+
+type RevokedTokenInterface interface {
+	Revoke(jti string, expiresAt time.Time) error
+	IsRevoked(jti string) (bool, error)
+}
@@ -0,0 +1,311 @@
+package migrations
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/waanvieira/api-users/internal/infra/database"
+	"gorm.io/gorm"
+)
+
+// Os arquivos .sql ficam embutidos no binário, assim o deploy não precisa levar a pasta junto
+// Padrão do nome: 0001_descricao.up.sql e 0001_descricao.down.sql
+// Quando um comando não funciona em todos os bancos criamos um arquivo só para o driver: 0001_descricao.mysql.up.sql
+// Uma migração que depende de um recurso opcional do banco começa com "-- requires: fts5" e só é aplicada quando ele existe
+//
+//go:embed sql/*.sql
+var files embed.FS
+
+var (
+	ErrNoDownMigration = errors.New("migration has no down script")
+	ErrInvalidFileName = errors.New("invalid migration file name")
+	ErrUnknownRequires = errors.New("unknown migration requirement")
+)
+
+// Prefixo da linha que indica o recurso que a migração precisa
+const requiresPrefix = "-- requires:"
+
+// Tabela onde guardamos quais versões já foram aplicadas no banco
+const tableName = "schema_migrations"
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+	// Recurso do banco que a migração precisa, vazio para as que rodam em qualquer banco
+	Requires string
+}
+
+type Status struct {
+	Migration Migration
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+type schemaMigration struct {
+	Version   int64     `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"size:255;not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (schemaMigration) TableName() string {
+	return tableName
+}
+
+type Migrator struct {
+	DB         *gorm.DB
+	Migrations []Migration
+	// Migrações que precisam de um recurso que o banco não tem, ficam de fora do up e do down
+	Unavailable []Migration
+}
+
+// NewMigrator carrega as migrações embutidas escolhendo os arquivos do driver que o gorm está usando
+func NewMigrator(db *gorm.DB) (*Migrator, error) {
+	migrations, err := Load(files, db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	m := &Migrator{DB: db}
+	for _, migration := range migrations {
+		ok, err := supports(db, migration.Requires)
+		if err != nil {
+			return nil, fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+		}
+		if ok {
+			m.Migrations = append(m.Migrations, migration)
+		} else {
+			m.Unavailable = append(m.Unavailable, migration)
+		}
+	}
+	return m, nil
+}
+
+// supports verifica se o banco tem o recurso pedido pela migração
+func supports(db *gorm.DB, requires string) (bool, error) {
+	switch requires {
+	case "":
+		return true, nil
+	case "fts5":
+		// O sqlite do driver só traz o FTS5 com a tag sqlite_fts5
+		return database.HasFTS5(db), nil
+	default:
+		return false, fmt.Errorf("%w: %q", ErrUnknownRequires, requires)
+	}
+}
+
+// parseRequires lê o "-- requires: recurso" do começo do script de up
+func parseRequires(up string) string {
+	for _, line := range strings.Split(up, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "--") {
+			break
+		}
+		if requires, ok := strings.CutPrefix(line, requiresPrefix); ok {
+			return strings.TrimSpace(requires)
+		}
+	}
+	return ""
+}
+
+// Load lê os arquivos da pasta sql e devolve as migrações ordenadas pela versão
+// O arquivo específico do driver tem prioridade sobre o arquivo genérico
+func Load(fsys fs.FS, driver string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, "sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	// Guarda se o script atual veio de um arquivo do driver, para o genérico não sobrescrever
+	specific := map[string]bool{}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".sql") {
+			continue
+		}
+		version, name, fileDriver, direction, err := parseFileName(e.Name())
+		if err != nil {
+			return nil, err
+		}
+		if fileDriver != "" && fileDriver != driver {
+			continue
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join("sql", e.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration %04d has two names: %q and %q", version, m.Name, name)
+		}
+
+		key := fmt.Sprintf("%d.%s", version, direction)
+		if specific[key] && fileDriver == "" {
+			continue
+		}
+		specific[key] = fileDriver != ""
+		if direction == "up" {
+			m.Up = string(content)
+			m.Requires = parseRequires(m.Up)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if strings.TrimSpace(m.Up) == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// parseFileName separa "0001_create_products.mysql.up.sql" em versão, nome, driver e direção
+func parseFileName(fileName string) (version int64, name, driver, direction string, err error) {
+	parts := strings.Split(strings.TrimSuffix(fileName, ".sql"), ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, "", "", "", fmt.Errorf("%w: %s", ErrInvalidFileName, fileName)
+	}
+	direction = parts[len(parts)-1]
+	if direction != "up" && direction != "down" {
+		return 0, "", "", "", fmt.Errorf("%w: %s", ErrInvalidFileName, fileName)
+	}
+	if len(parts) == 3 {
+		driver = parts[1]
+	}
+
+	prefix, name, found := strings.Cut(parts[0], "_")
+	if !found || name == "" {
+		return 0, "", "", "", fmt.Errorf("%w: %s", ErrInvalidFileName, fileName)
+	}
+	version, err = strconv.ParseInt(prefix, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, "", "", "", fmt.Errorf("%w: %s", ErrInvalidFileName, fileName)
+	}
+	return version, name, driver, direction, nil
+}
+
+func (m *Migrator) ensureTable() error {
+	return m.DB.AutoMigrate(&schemaMigration{})
+}
+
+func (m *Migrator) applied() (map[int64]schemaMigration, error) {
+	if err := m.ensureTable(); err != nil {
+		return nil, err
+	}
+	var rows []schemaMigration
+	if err := m.DB.Order("version").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]schemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// Pending retorna as migrações que ainda não foram aplicadas
+func (m *Migrator) Pending() ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, migration := range m.Migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up aplica todas as migrações pendentes em ordem, cada uma dentro da sua transação
+func (m *Migrator) Up() ([]Migration, error) {
+	pending, err := m.Pending()
+	if err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for _, migration := range pending {
+		err := m.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(migration.Up).Error; err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %04d_%s up: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Down desfaz as últimas n migrações aplicadas, da mais nova para a mais antiga
+func (m *Migrator) Down(n int) ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for i := len(m.Migrations) - 1; i >= 0 && len(done) < n; i-- {
+		migration := m.Migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if strings.TrimSpace(migration.Down) == "" {
+			return done, fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, ErrNoDownMigration)
+		}
+		err := m.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(migration.Down).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&schemaMigration{}, migration.Version).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %04d_%s down: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Status lista todas as migrações conhecidas indicando se já foram aplicadas
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	status := make([]Status, 0, len(m.Migrations))
+	for _, migration := range m.Migrations {
+		s := Status{Migration: migration}
+		if row, ok := applied[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			s.Applied = true
+			s.AppliedAt = &appliedAt
+		}
+		status = append(status, s)
+	}
+	return status, nil
+}
@@ -0,0 +1,239 @@
+package migrations
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/waanvieira/api-users/internal/entity"
+	databaseUser "github.com/waanvieira/api-users/internal/infra/database"
+	databaseCategory "github.com/waanvieira/api-users/internal/infra/database/category"
+	databaseProduct "github.com/waanvieira/api-users/internal/infra/database/product"
+	entityPkg "github.com/waanvieira/api-users/pkg/entity"
+	"github.com/waanvieira/api-users/pkg/filter"
+
+	// Banco em memória sqlite
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestLoadPrefersDriverSpecificFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sql/0002_second.up.sql":       {Data: []byte("generic 2 up")},
+		"sql/0001_first.up.sql":        {Data: []byte("generic up")},
+		"sql/0001_first.mysql.up.sql":  {Data: []byte("mysql up")},
+		"sql/0001_first.down.sql":      {Data: []byte("generic down")},
+		"sql/0001_first.sqlite.up.sql": {Data: []byte("sqlite up")},
+	}
+
+	migrations, err := Load(fsys, "mysql")
+	assert.NoError(t, err)
+	assert.Len(t, migrations, 2)
+	assert.Equal(t, int64(1), migrations[0].Version)
+	assert.Equal(t, "first", migrations[0].Name)
+	assert.Equal(t, "mysql up", migrations[0].Up)
+	assert.Equal(t, "generic down", migrations[0].Down)
+	assert.Equal(t, int64(2), migrations[1].Version)
+
+	migrations, err = Load(fsys, "postgres")
+	assert.NoError(t, err)
+	assert.Equal(t, "generic up", migrations[0].Up)
+}
+
+func TestLoadRequires(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sql/0001_first.up.sql":          {Data: []byte("CREATE TABLE first (id INT);")},
+		"sql/0002_search.sqlite.up.sql":  {Data: []byte("-- requires: fts5\n-- índice da busca\nCREATE VIRTUAL TABLE search USING fts5(name);")},
+		"sql/0003_unknown.sqlite.up.sql": {Data: []byte("-- comentário\n-- requires: teleport\nSELECT 1;")},
+	}
+	migrations, err := Load(fsys, "sqlite")
+	assert.NoError(t, err)
+	assert.Equal(t, "", migrations[0].Requires)
+	assert.Equal(t, "fts5", migrations[1].Requires)
+	assert.Equal(t, "teleport", migrations[2].Requires)
+
+	db := newTestDB(t)
+	ok, err := supports(db, "fts5")
+	assert.NoError(t, err)
+	assert.Equal(t, databaseUser.HasFTS5(db), ok)
+	_, err = supports(db, "teleport")
+	assert.ErrorIs(t, err, ErrUnknownRequires)
+}
+
+func TestLoadInvalidFileName(t *testing.T) {
+	_, err := Load(fstest.MapFS{"sql/create_products.up.sql": {Data: []byte("x")}}, "sqlite")
+	assert.ErrorIs(t, err, ErrInvalidFileName)
+
+	_, err = Load(fstest.MapFS{"sql/0001_products.sideways.sql": {Data: []byte("x")}}, "sqlite")
+	assert.ErrorIs(t, err, ErrInvalidFileName)
+}
+
+func TestEmbeddedMigrationsUpAndDown(t *testing.T) {
+	db := newTestDB(t)
+	migrator, err := NewMigrator(db)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, migrator.Migrations)
+
+	applied, err := migrator.Up()
+	assert.NoError(t, err)
+	assert.Len(t, applied, len(migrator.Migrations))
+
+	// Rodar de novo não aplica nada
+	applied, err = migrator.Up()
+	assert.NoError(t, err)
+	assert.Empty(t, applied)
+
+	status, err := migrator.Status()
+	assert.NoError(t, err)
+	for _, s := range status {
+		assert.True(t, s.Applied)
+		assert.NotNil(t, s.AppliedAt)
+	}
+
+	// As tabelas criadas pelas migrações precisam funcionar com os nossos repositórios
+	product, _ := entity.NewProduct("product test", entityPkg.MustParseMoney("10", "BRL"))
+	assert.NoError(t, databaseProduct.NewProduct(db).Create(product))
+	// O índice FTS5 só é criado quando o sqlite tem o recurso, sem ele a busca usa LIKE
+	assert.Equal(t, databaseUser.HasFTS5(db), db.Migrator().HasTable("products_fts"))
+	results, _, err := databaseProduct.NewProduct(db).Search([]databaseUser.SearchTerm{{Text: "test"}}, 1, 10)
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	user, _ := entity.NewUser("user test", "user@teste.com", "123456")
+	assert.NoError(t, databaseUser.NewUser(db).Create(user))
+	_, err = databaseUser.NewUser(db).FindByEmail("user@teste.com")
+	assert.NoError(t, err)
+	// O índice único não deixa cadastrar o mesmo email com outra grafia, mesmo sem passar pela entidade
+	duplicated := *user
+	duplicated.ID = entityPkg.NewID()
+	duplicated.Email = "User@Teste.com"
+	assert.Error(t, db.Create(&duplicated).Error)
+	// Vários produtos sem SKU são aceitos, mas o mesmo SKU não
+	withoutSKU, _ := entity.NewProduct("product without sku", entityPkg.MustParseMoney("10", "BRL"))
+	assert.NoError(t, db.Create(withoutSKU).Error)
+	withSKU, _ := entity.NewProduct("product with sku", entityPkg.MustParseMoney("10", "BRL"), entity.WithSKU("ABC-1"))
+	assert.NoError(t, db.Create(withSKU).Error)
+	sameSKU, _ := entity.NewProduct("same sku", entityPkg.MustParseMoney("10", "BRL"), entity.WithSKU("ABC-1"))
+	assert.Error(t, db.Create(sameSKU).Error)
+
+	// Categorias em árvore e o filtro de produtos com subcategorias no schema das migrações
+	root, _ := entity.NewCategory("root", nil)
+	child, _ := entity.NewCategory("child", &root.ID)
+	categoryDB := databaseCategory.NewCategory(db)
+	assert.NoError(t, categoryDB.Create(root))
+	assert.NoError(t, categoryDB.Create(child))
+	categorized, _ := entity.NewProduct("categorized", entityPkg.MustParseMoney("10", "BRL"), entity.WithCategories(*child))
+	assert.NoError(t, databaseProduct.NewProduct(db).Create(categorized))
+	products, err := databaseProduct.NewProduct(db).FindAll(0, 0, nil, databaseUser.ProductFilter{CategoryID: root.ID.String(), IncludeSubcategories: true})
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+	// Tags normalizadas e o filtro por tag no schema das migrações
+	tagged, _ := entity.NewProduct("tagged", entityPkg.MustParseMoney("10", "BRL"), entity.WithTags("Promoção", "verão"))
+	assert.NoError(t, databaseProduct.NewProduct(db).Create(tagged))
+	products, err = databaseProduct.NewProduct(db).FindAll(0, 0, nil, databaseUser.ProductFilter{Tags: []string{"promoção", "verão"}, MatchAllTags: true})
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+	assert.Len(t, products[0].Tags, 2)
+	// Expressão do ?filter= sobre as colunas criadas pelas migrações
+	expression, err := filter.Parse("name startswith 'tag' and price ge 10 and currency eq brl and created_at ge 2000-01-01")
+	assert.NoError(t, err)
+	products, err = databaseProduct.NewProduct(db).FindAll(0, 0, nil, databaseUser.ProductFilter{Expression: expression})
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+
+	reverted, err := migrator.Down(len(migrator.Migrations))
+	assert.NoError(t, err)
+	assert.Len(t, reverted, len(migrator.Migrations))
+	assert.False(t, db.Migrator().HasTable("products"))
+	assert.False(t, db.Migrator().HasTable("users"))
+	assert.False(t, db.Migrator().HasTable("products_fts"))
+
+	pending, err := migrator.Pending()
+	assert.NoError(t, err)
+	assert.Len(t, pending, len(migrator.Migrations))
+}
+
+func TestDownOnlyRevertsLastMigrations(t *testing.T) {
+	db := newTestDB(t)
+	migrator, err := NewMigrator(db)
+	assert.NoError(t, err)
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+
+	last := migrator.Migrations[len(migrator.Migrations)-1]
+	reverted, err := migrator.Down(1)
+	assert.NoError(t, err)
+	assert.Len(t, reverted, 1)
+	assert.Equal(t, last.Version, reverted[0].Version)
+
+	pending, err := migrator.Pending()
+	assert.NoError(t, err)
+	assert.Len(t, pending, 1)
+	assert.True(t, db.Migrator().HasTable("products"))
+}
+
+func TestCatalogMigrationActivatesExistingProducts(t *testing.T) {
+	db := newTestDB(t)
+	migrator, err := NewMigrator(db)
+	assert.NoError(t, err)
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+
+	// Volta até antes da migração do catálogo para cadastrar um produto "antigo"
+	downTo(t, migrator, "add_catalog_fields_to_products")
+	assert.NoError(t, db.Exec("INSERT INTO products (id, name, price, created_at) VALUES (?, ?, ?, CURRENT_TIMESTAMP)", entityPkg.NewID().String(), "old product", 10).Error)
+
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+	var product entity.Product
+	assert.NoError(t, db.First(&product).Error)
+	assert.Equal(t, entity.ProductStatusActive, product.Status)
+	assert.Nil(t, product.SKU)
+	assert.Zero(t, product.Stock)
+}
+
+func TestMoneyMigrationConvertsPricesToCents(t *testing.T) {
+	db := newTestDB(t)
+	migrator, err := NewMigrator(db)
+	assert.NoError(t, err)
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+
+	downTo(t, migrator, "convert_price_to_money")
+	prices := map[string]float64{"a": 10.5, "b": 19.99, "c": 0.07}
+	for name, price := range prices {
+		assert.NoError(t, db.Exec("INSERT INTO products (id, name, price, created_at) VALUES (?, ?, ?, CURRENT_TIMESTAMP)", entityPkg.NewID().String(), name, price).Error)
+	}
+
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+	var products []entity.Product
+	assert.NoError(t, db.Order("name").Find(&products).Error)
+	assert.Len(t, products, 3)
+	assert.Equal(t, entityPkg.MustParseMoney("10.50", "BRL"), products[0].Price)
+	assert.Equal(t, entityPkg.MustParseMoney("19.99", "BRL"), products[1].Price)
+	assert.Equal(t, entityPkg.MustParseMoney("0.07", "BRL"), products[2].Price)
+}
+
+// downTo reverte as migrações até antes da migração informada, para cadastrar dados no formato antigo
+func downTo(t *testing.T, migrator *Migrator, name string) {
+	t.Helper()
+	var steps int
+	for i, m := range migrator.Migrations {
+		if m.Name == name {
+			steps = len(migrator.Migrations) - i
+		}
+	}
+	if steps == 0 {
+		t.Fatalf("migration %s not found", name)
+	}
+	_, err := migrator.Down(steps)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS products (
+    id VARCHAR(36) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    price DOUBLE PRECISION NOT NULL,
+    created_at TIMESTAMP NULL,
+    PRIMARY KEY (id)
+);
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    jti VARCHAR(64) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NULL,
+    PRIMARY KEY (jti)
+);
+CREATE INDEX idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
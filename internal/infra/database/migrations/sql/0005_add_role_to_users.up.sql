@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'viewer';
+-- Quem já existia podia criar e alterar produtos, continua podendo
+UPDATE users SET role = 'editor';
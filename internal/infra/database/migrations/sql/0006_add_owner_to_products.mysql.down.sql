@@ -0,0 +1,2 @@
+DROP INDEX idx_products_owner_id ON products;
+ALTER TABLE products DROP COLUMN owner_id;
@@ -0,0 +1,3 @@
+-- Produtos que já existiam ficam sem dono, apenas um admin consegue alterar ou remover
+ALTER TABLE products ADD COLUMN owner_id VARCHAR(36) NULL;
+CREATE INDEX idx_products_owner_id ON products (owner_id);
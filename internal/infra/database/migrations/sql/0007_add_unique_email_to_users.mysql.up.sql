@@ -0,0 +1,4 @@
+-- Os emails passam a ser salvos normalizados, se já existirem emails repetidos a migração falha e eles precisam ser resolvidos antes
+UPDATE users SET email = LOWER(TRIM(email));
+-- O collation padrão do MySQL já compara sem diferenciar maiúsculas e minúsculas
+CREATE UNIQUE INDEX idx_users_email ON users (email);
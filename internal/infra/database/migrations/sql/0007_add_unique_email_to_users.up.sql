@@ -0,0 +1,3 @@
+-- Os emails passam a ser salvos normalizados, se já existirem emails repetidos a migração falha e eles precisam ser resolvidos antes
+UPDATE users SET email = LOWER(TRIM(email));
+CREATE UNIQUE INDEX idx_users_email ON users (LOWER(email));
@@ -0,0 +1,2 @@
+-- Produtos que já existiam começam na versão 1
+ALTER TABLE products ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,3 @@
+ALTER TABLE products ADD COLUMN updated_at TIMESTAMP NULL;
+-- Produtos que já existiam nunca foram alterados desde o cadastro
+UPDATE products SET updated_at = created_at;
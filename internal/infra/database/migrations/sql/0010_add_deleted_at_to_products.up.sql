@@ -0,0 +1,3 @@
+-- Lixeira: o DELETE passa a só preencher deleted_at
+ALTER TABLE products ADD COLUMN deleted_at TIMESTAMP NULL;
+CREATE INDEX idx_products_deleted_at ON products (deleted_at);
@@ -0,0 +1,5 @@
+DROP INDEX idx_products_sku;
+ALTER TABLE products DROP COLUMN status;
+ALTER TABLE products DROP COLUMN stock;
+ALTER TABLE products DROP COLUMN sku;
+ALTER TABLE products DROP COLUMN description;
@@ -0,0 +1,8 @@
+ALTER TABLE products ADD COLUMN description VARCHAR(2000) NOT NULL DEFAULT '';
+ALTER TABLE products ADD COLUMN sku VARCHAR(64) NULL;
+ALTER TABLE products ADD COLUMN stock BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE products ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'draft';
+-- O SKU é opcional, o índice único aceita vários produtos sem SKU (NULL)
+CREATE UNIQUE INDEX idx_products_sku ON products (sku);
+-- Produtos que já existiam estavam à venda, apenas os novos começam como rascunho
+UPDATE products SET status = 'active';
@@ -0,0 +1,5 @@
+ALTER TABLE products ADD COLUMN price DOUBLE PRECISION NOT NULL DEFAULT 0;
+-- Volta para o valor decimal, o iene não tem centavos
+UPDATE products SET price = CASE price_currency WHEN 'JPY' THEN price_amount ELSE price_amount / 100.0 END;
+ALTER TABLE products DROP COLUMN price_currency;
+ALTER TABLE products DROP COLUMN price_amount;
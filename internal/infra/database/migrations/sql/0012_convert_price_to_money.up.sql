@@ -0,0 +1,5 @@
+-- O preço passa a ser gravado em centavos com a moeda, os preços antigos eram em reais
+ALTER TABLE products ADD COLUMN price_amount BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE products ADD COLUMN price_currency VARCHAR(3) NOT NULL DEFAULT 'BRL';
+UPDATE products SET price_amount = ROUND(price * 100), price_currency = 'BRL';
+ALTER TABLE products DROP COLUMN price;
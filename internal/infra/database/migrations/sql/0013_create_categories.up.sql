@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS categories (
+    id VARCHAR(36) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    parent_id VARCHAR(36) NULL,
+    created_at TIMESTAMP NULL,
+    updated_at TIMESTAMP NULL,
+    PRIMARY KEY (id)
+);
+CREATE INDEX idx_categories_parent_id ON categories (parent_id);
+CREATE TABLE IF NOT EXISTS product_categories (
+    product_id VARCHAR(36) NOT NULL,
+    category_id VARCHAR(36) NOT NULL,
+    PRIMARY KEY (product_id, category_id)
+);
+CREATE INDEX idx_product_categories_category_id ON product_categories (category_id);
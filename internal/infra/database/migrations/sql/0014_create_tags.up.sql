@@ -0,0 +1,12 @@
+-- A tag é apenas o nome normalizado, que também é a chave
+CREATE TABLE IF NOT EXISTS tags (
+    name VARCHAR(50) NOT NULL,
+    PRIMARY KEY (name)
+);
+CREATE TABLE IF NOT EXISTS product_tags (
+    product_id VARCHAR(36) NOT NULL,
+    tag_name VARCHAR(50) NOT NULL,
+    PRIMARY KEY (product_id, tag_name)
+);
+-- Busca dos produtos pela tag e contagem do GET /tags
+CREATE INDEX idx_product_tags_tag_name ON product_tags (tag_name);
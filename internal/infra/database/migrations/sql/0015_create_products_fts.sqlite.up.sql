@@ -0,0 +1,21 @@
+-- requires: fts5
+-- Índice full-text da busca de produtos, apenas no sqlite compilado com FTS5 (go build -tags sqlite_fts5, o padrão do Makefile)
+-- Sem o FTS5 a migração fica de fora e a busca usa LIKE, como no MySQL e no Postgres
+-- O product_id não é indexado, só liga a linha ao produto; o remove_diacritics faz "cafe" encontrar "café"
+-- Não usamos o rowid do products como chave porque no sqlite ele pode mudar depois de um VACUUM
+CREATE VIRTUAL TABLE IF NOT EXISTS products_fts USING fts5(
+    product_id UNINDEXED, name, description, tokenize = 'unicode61 remove_diacritics 2'
+);
+-- Os triggers mantêm o índice em sincronia com a tabela products
+CREATE TRIGGER IF NOT EXISTS products_fts_insert AFTER INSERT ON products BEGIN
+    INSERT INTO products_fts (product_id, name, description) VALUES (new.id, new.name, new.description);
+END;
+CREATE TRIGGER IF NOT EXISTS products_fts_update AFTER UPDATE OF name, description ON products BEGIN
+    DELETE FROM products_fts WHERE product_id = old.id;
+    INSERT INTO products_fts (product_id, name, description) VALUES (new.id, new.name, new.description);
+END;
+CREATE TRIGGER IF NOT EXISTS products_fts_delete AFTER DELETE ON products BEGIN
+    DELETE FROM products_fts WHERE product_id = old.id;
+END;
+-- Indexa os produtos que já existem
+INSERT INTO products_fts (product_id, name, description) SELECT id, name, description FROM products;
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Keyset é a posição de um registro na ordenação: o valor de cada campo do sort, em texto, e o id que desempata
+type Keyset struct {
+	Values []string `json:"v"`
+	ID     string   `json:"id"`
+}
+
+// CursorPage pede os Limit registros depois de After ou antes de Before, sem nenhum dos dois começa do início
+type CursorPage struct {
+	Limit  int
+	After  *Keyset
+	Before *Keyset
+	// Conta o total de registros do filtro, é uma consulta a mais
+	WithTotal bool
+}
+
+// PageInfo descreve a página devolvida por um CursorPage
+type PageInfo struct {
+	// Posição do primeiro e do último registro, nil quando a página está vazia
+	First, Last *Keyset
+	HasNext     bool
+	HasPrev     bool
+	// Total de registros do filtro, -1 quando não foi pedido
+	Total int64
+}
+
+// SortString devolve a ordenação no formato do ?sort=, sem ordenação informada vale created_at
+// Os cursores guardam esse texto para não serem usados com outra ordenação
+func SortString(sort []SortField) string {
+	if len(sort) == 0 {
+		return "created_at"
+	}
+	keys := make([]string, len(sort))
+	for i, s := range sort {
+		keys[i] = s.Field
+		if s.Desc {
+			keys[i] = "-" + s.Field
+		}
+	}
+	return strings.Join(keys, ",")
+}
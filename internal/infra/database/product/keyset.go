@@ -0,0 +1,192 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/waanvieira/api-users/internal/entity"
+	"github.com/waanvieira/api-users/internal/infra/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// sortColumn é um campo aceito no ?sort=: a expressão usada no ORDER BY e na condição do cursor,
+// o valor do campo no produto em texto (vai dentro do cursor) e a conversão de volta para o argumento da consulta
+type sortColumn struct {
+	expr  string
+	value func(p *entity.Product) string
+	arg   func(value string) (interface{}, error)
+}
+
+// productSortColumns são os campos aceitos no ?sort=
+// price ordena pelo valor na menor unidade, com moedas diferentes ordene antes por currency
+// O sku nulo é ordenado como texto vazio, assim fica na mesma posição em todos os bancos
+var productSortColumns = map[string]sortColumn{
+	"name":       {expr: "name", value: func(p *entity.Product) string { return p.Name }, arg: textArg},
+	"price":      {expr: "price_amount", value: func(p *entity.Product) string { return strconv.FormatInt(p.Price.Amount, 10) }, arg: intArg},
+	"currency":   {expr: "price_currency", value: func(p *entity.Product) string { return p.Price.Currency }, arg: textArg},
+	"stock":      {expr: "stock", value: func(p *entity.Product) string { return strconv.FormatInt(p.Stock, 10) }, arg: intArg},
+	"status":     {expr: "status", value: func(p *entity.Product) string { return string(p.Status) }, arg: textArg},
+	"sku":        {expr: "COALESCE(sku, '')", value: skuValue, arg: textArg},
+	"created_at": {expr: "created_at", value: func(p *entity.Product) string { return p.CreatedAt.Format(time.RFC3339Nano) }, arg: timeArg},
+	"updated_at": {expr: "updated_at", value: func(p *entity.Product) string { return p.UpdatedAt.Format(time.RFC3339Nano) }, arg: timeArg},
+}
+
+// O id desempata produtos com os mesmos valores, assim a paginação não repete nem pula produtos
+var idColumn = sortColumn{expr: "id", value: func(p *entity.Product) string { return p.ID.String() }, arg: textArg}
+
+type sortKey struct {
+	column sortColumn
+	desc   bool
+}
+
+// sortKeys valida a ordenação pedida e acrescenta o id no final, sem ordenação informada vale created_at
+func sortKeys(sort []database.SortField) ([]sortKey, error) {
+	if len(sort) == 0 {
+		sort = []database.SortField{{Field: "created_at"}}
+	}
+	keys := make([]sortKey, 0, len(sort)+1)
+	for _, s := range sort {
+		column, ok := productSortColumns[s.Field]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown field %q, use name, price, currency, stock, status, sku, created_at or updated_at", database.ErrInvalidSort, s.Field)
+		}
+		keys = append(keys, sortKey{column: column, desc: s.Desc})
+	}
+	return append(keys, sortKey{column: idColumn}), nil
+}
+
+// orderBy aplica a ordenação, com reverse todas as direções são invertidas (usado para buscar a página anterior)
+func orderBy(query *gorm.DB, keys []sortKey, reverse bool) *gorm.DB {
+	for _, k := range keys {
+		query = query.Order(clause.OrderByColumn{Column: clause.Column{Name: k.column.expr, Raw: true}, Desc: k.desc != reverse})
+	}
+	return query
+}
+
+// keysetCondition monta a condição dos produtos depois da posição do cursor na ordenação
+// (ou antes, com reverse), ex: para -price,name
+// (price_amount < ?) OR (price_amount = ? AND name > ?) OR (price_amount = ? AND name = ? AND id > ?)
+func keysetCondition(keys []sortKey, position database.Keyset, reverse bool) (string, []interface{}, error) {
+	if len(position.Values) != len(keys)-1 {
+		return "", nil, database.ErrInvalidCursor
+	}
+	values := make([]interface{}, len(keys))
+	for i, k := range keys[:len(keys)-1] {
+		value, err := k.column.arg(position.Values[i])
+		if err != nil {
+			return "", nil, database.ErrInvalidCursor
+		}
+		values[i] = value
+	}
+	values[len(keys)-1] = position.ID
+
+	conditions := make([]string, len(keys))
+	var args []interface{}
+	for i, k := range keys {
+		parts := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			parts = append(parts, keys[j].column.expr+" = ?")
+			args = append(args, values[j])
+		}
+		op := "<"
+		if k.desc == reverse {
+			op = ">"
+		}
+		parts = append(parts, k.column.expr+" "+op+" ?")
+		args = append(args, values[i])
+		conditions[i] = "(" + strings.Join(parts, " AND ") + ")"
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")", args, nil
+}
+
+// keysetOf devolve a posição do produto na ordenação, é o que vai dentro do cursor
+func keysetOf(keys []sortKey, product *entity.Product) *database.Keyset {
+	position := &database.Keyset{Values: make([]string, 0, len(keys)-1), ID: product.ID.String()}
+	for _, k := range keys[:len(keys)-1] {
+		position.Values = append(position.Values, k.column.value(product))
+	}
+	return position
+}
+
+// FindPage busca até page.Limit produtos depois de page.After ou antes de page.Before
+// Diferente do offset, produtos cadastrados ou removidos entre uma página e outra não fazem a listagem repetir ou pular itens
+func (p *Product) FindPage(sort []database.SortField, page database.CursorPage, filter database.ProductFilter) ([]entity.Product, database.PageInfo, error) {
+	info := database.PageInfo{Total: -1}
+	keys, err := sortKeys(sort)
+	if err != nil {
+		return nil, info, err
+	}
+	if page.WithTotal {
+		if info.Total, err = p.Count(filter); err != nil {
+			return nil, info, err
+		}
+	}
+	query, err := p.applyFilter(withAssociations(p.DB), filter)
+	if err != nil {
+		return nil, info, err
+	}
+
+	// A página anterior é buscada na ordem inversa a partir do cursor e depois desvirada
+	backward := page.Before != nil
+	position := page.After
+	if backward {
+		position = page.Before
+	}
+	if position != nil {
+		condition, args, err := keysetCondition(keys, *position, backward)
+		if err != nil {
+			return nil, info, err
+		}
+		query = query.Where(condition, args...)
+	}
+
+	// Um produto a mais só para saber se existe outra página
+	var products []entity.Product
+	if err := orderBy(query, keys, backward).Limit(page.Limit + 1).Find(&products).Error; err != nil {
+		return nil, info, err
+	}
+	more := len(products) > page.Limit
+	if more {
+		products = products[:page.Limit]
+	}
+	if backward {
+		for i, j := 0, len(products)-1; i < j; i, j = i+1, j-1 {
+			products[i], products[j] = products[j], products[i]
+		}
+		info.HasPrev, info.HasNext = more, true
+	} else {
+		info.HasNext, info.HasPrev = more, page.After != nil
+	}
+	if len(products) > 0 {
+		info.First = keysetOf(keys, &products[0])
+		info.Last = keysetOf(keys, &products[len(products)-1])
+	}
+	return products, info, nil
+}
+
+func skuValue(p *entity.Product) string {
+	if p.SKU == nil {
+		return ""
+	}
+	return *p.SKU
+}
+
+func textArg(value string) (interface{}, error) {
+	return value, nil
+}
+
+func intArg(value string) (interface{}, error) {
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// timeArg volta a data para o fuso local, o sqlite compara as datas como texto no fuso em que foram gravadas
+func timeArg(value string) (interface{}, error) {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil, err
+	}
+	return t.Local(), nil
+}
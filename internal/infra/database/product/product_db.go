@@ -1,13 +1,29 @@
 package database
 
 import (
+	"context"
+	"errors"
+	"log"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
 	"github.com/waanvieira/api-users/internal/entity"
+	"github.com/waanvieira/api-users/internal/infra/database"
+	entityPkg "github.com/waanvieira/api-users/pkg/entity"
+	"github.com/waanvieira/api-users/pkg/filter"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // Indica que nossa struct Product que seria do DB recebe a variável DB do gorm
 type Product struct {
 	DB *gorm.DB
+	// Busca pelo índice FTS5 quando a tabela existe, sem ela a busca usa LIKE
+	searchOnce sync.Once
+	fullText   bool
 }
 
 // Cria uma struct para compor a nossa "classe" e o restante é do próprio ORM
@@ -17,17 +33,96 @@ func NewProduct(db *gorm.DB) *Product {
 
 // Não retorna a nossa entity, retorna apenas um erro, então em algum lugar podemos chamar essa função e verifica apenas se tem um erro
 func (p *Product) Create(product *entity.Product) error {
-	return p.DB.Create(product).Error
+	// As categorias já existem, o Omit grava apenas a ligação em product_categories; as tags novas são criadas
+	return translateProductError(p.DB.Omit("Categories.*").Create(product).Error)
 }
 
-func (p *Product) FindAll(page int, limit int, sort string) ([]entity.Product, error) {
-	var products []entity.Product
-	// Iniciamos a variavel de erro, se por acaso der algum erro retorna um erro
-	var err error
+// withAssociations carrega as categorias e as tags junto com os produtos, em ordem alfabética
+func withAssociations(db *gorm.DB) *gorm.DB {
+	byName := func(db *gorm.DB) *gorm.DB {
+		return db.Order("name")
+	}
+	return db.Preload("Categories", byName).Preload("Tags", byName)
+}
+
+// productFields são os campos aceitos no ?filter= da listagem e a coluna de cada um
+var productFields = filter.Fields{
+	"name":        {Column: "name", Type: filter.String},
+	"description": {Column: "description", Type: filter.String},
+	"sku":         {Column: "sku", Type: filter.String},
+	"status": {Column: "status", Type: filter.String, Values: []string{
+		string(entity.ProductStatusDraft), string(entity.ProductStatusActive), string(entity.ProductStatusArchived),
+	}},
+	"stock":      {Column: "stock", Type: filter.Number},
+	"price":      {Type: filter.Number, Condition: priceCondition},
+	"currency":   {Column: "price_currency", Type: filter.String, Values: entityPkg.SupportedCurrencies()},
+	"created_at": {Column: "created_at", Type: filter.Time},
+	"updated_at": {Column: "updated_at", Type: filter.Time},
+}
+
+// priceCondition compara o preço na unidade de cada moeda, já que o banco guarda centavos (ou a menor unidade)
+// Assim "price gt 10" vira price_amount > 1000 para BRL e price_amount > 10 para JPY
+func priceCondition(op filter.Operator, values []string) (string, []interface{}, error) {
+	currenciesByScale := map[int][]string{}
+	for _, currency := range entityPkg.SupportedCurrencies() {
+		scale := entityPkg.CurrencyScale(currency)
+		currenciesByScale[scale] = append(currenciesByScale[scale], currency)
+	}
+	scales := make([]int, 0, len(currenciesByScale))
+	for scale := range currenciesByScale {
+		scales = append(scales, scale)
+	}
+	sort.Ints(scales)
+
+	conditions := make([]string, 0, len(scales))
+	var args []interface{}
+	for _, scale := range scales {
+		amounts := make([]interface{}, len(values))
+		for i, value := range values {
+			amounts[i] = minorUnits(value, scale)
+		}
+		if op == filter.In {
+			conditions = append(conditions, "(price_currency IN ? AND price_amount IN ?)")
+			args = append(args, currenciesByScale[scale], amounts)
+			continue
+		}
+		conditions = append(conditions, "(price_currency IN ? AND price_amount "+op.SQL()+" ?)")
+		args = append(args, currenciesByScale[scale], amounts[0])
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")", args, nil
+}
+
+// minorUnits converte o valor decimal do filtro para a menor unidade da moeda
+// Com mais casas que a moeda tem o valor fica fracionado, ex: 10.5 em JPY, e a comparação continua correta
+func minorUnits(value string, scale int) interface{} {
+	amount, _ := new(big.Rat).SetString(value)
+	amount.Mul(amount, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)))
+	if amount.IsInt() && amount.Num().IsInt64() {
+		return amount.Num().Int64()
+	}
+	f, _ := amount.Float64()
+	return f
+}
 
-	if sort != "" && sort != "asc" && sort != "desc" {
-		sort = "asc"
+// categoryTree é o subselect com a categoria e todas as subcategorias dela
+// O UNION (sem ALL) para de descer se por algum motivo existir um ciclo no banco
+const categoryTree = `WITH RECURSIVE tree(id) AS (
+	SELECT id FROM categories WHERE id = ?
+	UNION
+	SELECT c.id FROM categories c JOIN tree ON c.parent_id = tree.id
+) SELECT id FROM tree`
+
+func (p *Product) FindAll(page int, limit int, sort []database.SortField, filter database.ProductFilter) ([]entity.Product, error) {
+	var products []entity.Product
+	query, err := p.applyFilter(withAssociations(p.DB), filter)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := sortKeys(sort)
+	if err != nil {
+		return nil, err
 	}
+	query = orderBy(query, keys, false)
 	if page != 0 && limit != 0 {
 		// Aqui informamos que na paginação o page -1 para sempre subtrair 1 e passando o sort, se encontra algum registro hidrata a variavel "products" se não retorna um erro
 		// Nesse caso se existe registros e deu tudo certo a nossa variável "products" que vai ser hidratada, se der algum erro vai hidratar a variável error
@@ -37,41 +132,274 @@ func (p *Product) FindAll(page int, limit int, sort string) ([]entity.Product, e
 		// return product
 		// No caso do GO e o GORM se vem o erro hidrata a variável err para retornar, porque podemos retornar 2 parametros na mesma função
 		// Nesse caso a variável error vai retornar como nil, que seria em branco
-		err = p.DB.Limit(limit).Offset((page - 1) * limit).Order("created_at " + sort).Find(&products).Error
+		err = query.Limit(limit).Offset((page - 1) * limit).Find(&products).Error
 	} else {
 		// Aqui usa da mesma base porém aqui faz um find e apenas ordena, retorna todos os dados apenas ordenado
-		err = p.DB.Order("created_at " + sort).Find(&products).Error
+		err = query.Find(&products).Error
 	}
 
 	return products, err
 }
 
+// Count conta os produtos do filtro, sem os da lixeira
+func (p *Product) Count(filter database.ProductFilter) (int64, error) {
+	query, err := p.applyFilter(p.DB.Model(&entity.Product{}), filter)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	err = query.Count(&total).Error
+	return total, err
+}
+
+// applyFilter aplica as condições da listagem (dono, tags, expressão e categoria) na consulta
+func (p *Product) applyFilter(query *gorm.DB, filter database.ProductFilter) (*gorm.DB, error) {
+	if filter.OwnerID != "" {
+		query = query.Where("owner_id = ?", filter.OwnerID)
+	}
+	if len(filter.Tags) > 0 {
+		tagged := p.DB.Table("product_tags").Select("product_id").Where("tag_name IN ?", filter.Tags)
+		if filter.MatchAllTags {
+			// Só os produtos que têm todas as tags, as tags do filtro já vêm sem repetição
+			tagged = tagged.Group("product_id").Having("COUNT(*) = ?", len(filter.Tags))
+		}
+		query = query.Where("id IN (?)", tagged)
+	}
+	if filter.Expression != nil {
+		condition, args, err := compileFilter(filter.Expression)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where(condition, args...)
+	}
+	if filter.CategoryID != "" {
+		linked := p.DB.Table("product_categories").Select("product_id").Where("category_id = ?", filter.CategoryID)
+		if filter.IncludeSubcategories {
+			linked = p.DB.Table("product_categories").Select("product_id").Where("category_id IN (?)", p.DB.Raw(categoryTree, filter.CategoryID))
+		}
+		query = query.Where("id IN (?)", linked)
+	}
+	return query, nil
+}
+
+// compileFilter traduz a expressão do ?filter= para SQL com parâmetros, apenas com os campos de productFields
+func compileFilter(expression filter.Node) (string, []interface{}, error) {
+	return filter.Compile(expression, productFields)
+}
+
 // (u *Product) - indica que a função é dessa nossa struct
 // (id string) Nossao paramaetro que é uma string
 // (*entity.Product, error) - Significa que retorna um ponteiro de Product da nossa entity ou retorna um erro
 func (p *Product) FindByID(id string) (*entity.Product, error) {
 	var product entity.Product
 	// Os dados são preenchidos no Firs(&product), significa que não deu nenhum erro e vai hidratar o nosso ponteiro
-	if err := p.DB.Where("id = ?", id).First(&product).Error; err != nil {
+	if err := withAssociations(p.DB).Where("id = ?", id).First(&product).Error; err != nil {
 		return nil, err
 	}
 
 	return &product, nil
 }
 
+// Update grava o produto apenas se ele ainda estiver na versão informada em product.Version e incrementa a versão
+// Se outra requisição alterou o produto antes retorna entity.ErrVersionConflict, assim uma alteração não sobrescreve a outra
 func (p *Product) Update(product *entity.Product) error {
-	// Verifica se o registro existe, se deixar apenas com o "Save" se o registro não existir ele vai gravar de qualquer forma
-	_, err := p.FindByID(product.ID.String())
+	next := *product
+	next.Version = product.Version + 1
+	next.UpdatedAt = time.Now()
+	err := p.DB.Transaction(func(tx *gorm.DB) error {
+		// Select("*") grava também os campos com valor zero, igual ao Save, e o WHERE da versão faz a verificação no próprio UPDATE
+		result := tx.Model(&next).Where("version = ?", product.Version).Select("*").Omit("created_at", "deleted_at", clause.Associations).Updates(&next)
+		if result.Error != nil {
+			return translateProductError(result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return versionError(tx, product.ID.String())
+		}
+		// As tags fazem parte do produto, as categorias só mudam pelo SetCategories
+		return tx.Model(&next).Association("Tags").Replace(next.Tags)
+	})
+	if err != nil {
+		return err
+	}
+	product.Version = next.Version
+	product.UpdatedAt = next.UpdatedAt
+	return nil
+}
+
+// SetCategories troca as categorias do produto, com a mesma verificação de versão do Update
+func (p *Product) SetCategories(product *entity.Product, categories []entity.Category) error {
+	now := time.Now()
+	err := p.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entity.Product{}).Where("id = ? AND version = ?", product.ID, product.Version).
+			Updates(map[string]interface{}{"version": product.Version + 1, "updated_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return versionError(tx, product.ID.String())
+		}
+		return tx.Model(product).Omit("Categories.*").Association("Categories").Replace(categories)
+	})
 	if err != nil {
 		return err
 	}
-	return p.DB.Save(&product).Error
+	product.Version++
+	product.UpdatedAt = now
+	product.Categories = categories
+	return nil
+}
+
+// versionError é chamado quando o UPDATE com a versão não alterou nenhuma linha: ou o produto não existe ou está em outra versão
+func versionError(db *gorm.DB, id string) error {
+	var count int64
+	if err := db.Model(&entity.Product{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return entity.ErrVersionConflict
+}
+
+// translateProductError troca a violação do índice único de SKU pelo erro do domínio
+func translateProductError(err error) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return entity.ErrSKUAlreadyExists
+	}
+	return err
+}
+
+// Delete move o produto para a lixeira preenchendo o deleted_at, o registro só sai do banco no Purge
+// Como no Update, o WHERE da versão faz a verificação no próprio UPDATE: uma alteração feita depois
+// do If-Match faz a remoção falhar; a versão muda para invalidar os ETags de antes da remoção
+func (p *Product) Delete(id string, version int64) error {
+	now := time.Now()
+	result := p.DB.Unscoped().Model(&entity.Product{}).
+		Where("id = ? AND version = ? AND deleted_at IS NULL", id, version).
+		Updates(map[string]interface{}{
+			"deleted_at": now,
+			"version":    gorm.Expr("version + 1"),
+			"updated_at": now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return versionError(p.DB, id)
+	}
+	return nil
+}
+
+// trash são os produtos da lixeira, o Unscoped tira o filtro de deleted_at que o gorm coloca sozinho
+func (p *Product) trash() *gorm.DB {
+	return p.DB.Unscoped().Where("deleted_at IS NOT NULL")
+}
+
+// FindDeleted lista a lixeira, os removidos mais recentemente primeiro
+func (p *Product) FindDeleted(page int, limit int, filter database.ProductFilter) ([]entity.Product, error) {
+	var products []entity.Product
+	query := withAssociations(p.trash())
+	if filter.OwnerID != "" {
+		query = query.Where("owner_id = ?", filter.OwnerID)
+	}
+	if page != 0 && limit != 0 {
+		query = query.Limit(limit).Offset((page - 1) * limit)
+	}
+	err := query.Order("deleted_at desc").Order("id").Find(&products).Error
+	return products, err
+}
+
+// FindDeletedByID busca um produto que está na lixeira
+func (p *Product) FindDeletedByID(id string) (*entity.Product, error) {
+	var product entity.Product
+	if err := withAssociations(p.trash()).Where("id = ?", id).First(&product).Error; err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// Restore tira o produto da lixeira se ele ainda estiver na versão informada, como no Delete
+// A versão muda de novo para invalidar os ETags da lixeira
+func (p *Product) Restore(id string, version int64) (*entity.Product, error) {
+	result := p.trash().Model(&entity.Product{}).Where("id = ? AND version = ?", id, version).Updates(map[string]interface{}{
+		"deleted_at": nil,
+		"version":    gorm.Expr("version + 1"),
+		"updated_at": time.Now(),
+	})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	// Nenhuma linha alterada: ou o produto não está na lixeira ou está em outra versão
+	if result.RowsAffected == 0 {
+		if _, err := p.FindDeletedByID(id); err != nil {
+			return nil, err
+		}
+		return nil, entity.ErrVersionConflict
+	}
+	return p.FindByID(id)
 }
 
-func (p *Product) Delete(id string) error {
-	product, err := p.FindByID(id)
+// Purge apaga de vez um produto que está na lixeira
+func (p *Product) Purge(id string) error {
+	purged, err := p.purge("id = ?", id)
 	if err != nil {
 		return err
 	}
-	return p.DB.Delete(product).Error
+	if purged == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// PurgeDeletedBefore apaga de vez os produtos que estão na lixeira desde antes da data informada
+func (p *Product) PurgeDeletedBefore(before time.Time) (int64, error) {
+	return p.purge("deleted_at < ?", before)
+}
+
+// purge apaga os produtos da lixeira que batem com a condição junto com a ligação com as categorias
+// Os ids são buscados antes porque o MySQL não aceita DELETE com subselect na mesma tabela
+func (p *Product) purge(query interface{}, args ...interface{}) (int64, error) {
+	var purged int64
+	err := p.DB.Transaction(func(tx *gorm.DB) error {
+		var ids []string
+		if err := tx.Unscoped().Model(&entity.Product{}).Where("deleted_at IS NOT NULL").Where(query, args...).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		for _, join := range []string{"product_categories", "product_tags"} {
+			if err := tx.Exec("DELETE FROM "+join+" WHERE product_id IN ?", ids).Error; err != nil {
+				return err
+			}
+		}
+		result := tx.Unscoped().Where("id IN ?", ids).Delete(&entity.Product{})
+		purged = result.RowsAffected
+		return result.Error
+	})
+	return purged, err
+}
+
+// Intervalo da limpeza da lixeira quando o informado não é positivo, o mesmo padrão do PRODUCT_TRASH_PURGE_INTERVAL
+const defaultPurgeInterval = time.Hour
+
+// StartPurge esvazia a cada intervalo os produtos que estão na lixeira há mais que a retenção, até o contexto ser cancelado
+func (p *Product) StartPurge(ctx context.Context, interval, retention time.Duration) {
+	// O time.NewTicker entra em pânico com intervalo zero ou negativo
+	if interval <= 0 {
+		log.Printf("purge deleted products: invalid interval %s, using %s", interval, defaultPurgeInterval)
+		interval = defaultPurgeInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := p.PurgeDeletedBefore(time.Now().Add(-retention)); err != nil {
+				log.Printf("purge deleted products: %v", err)
+			}
+		}
+	}
 }
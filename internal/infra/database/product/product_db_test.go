@@ -1,12 +1,17 @@
 package database
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/waanvieira/api-users/internal/entity"
+	"github.com/waanvieira/api-users/internal/infra/database"
+	entityPkg "github.com/waanvieira/api-users/pkg/entity"
+	"github.com/waanvieira/api-users/pkg/filter"
 
 	// Banco em memória sqlite
 	"gorm.io/driver/sqlite"
@@ -21,7 +26,7 @@ func TestCreateProduct(t *testing.T) {
 	// Fazendo um migrate da tabela de usuário
 	db.AutoMigrate(&entity.Product{})
 	// Cria a nossa entity de product
-	product, _ := entity.NewProduct("product test", 10)
+	product, _ := entity.NewProduct("product test", entityPkg.MustParseMoney("10", "BRL"))
 	// Basicamente iniciamos a struct
 	productDB := NewProduct(db)
 
@@ -47,7 +52,7 @@ func TestUpdateProduct(t *testing.T) {
 	// Fazendo um migrate da tabela de usuário
 	db.AutoMigrate(&entity.Product{})
 	// Cria a nossa entity de product
-	product, _ := entity.NewProduct("product test", 10)
+	product, _ := entity.NewProduct("product test", entityPkg.MustParseMoney("10", "BRL"))
 	// Basicamente iniciamos a struct
 	productDB := NewProduct(db)
 
@@ -56,17 +61,76 @@ func TestUpdateProduct(t *testing.T) {
 	// verificamos se não deu nenhum erro
 	assert.Nil(t, err)
 
+	createdAt := product.CreatedAt
 	product.Name = "name updated"
-	product.Price = 20
+	product.Price = entityPkg.MustParseMoney("20", "BRL")
 
 	err = productDB.Update(product)
+	assert.Nil(t, err)
+	assert.True(t, product.UpdatedAt.After(createdAt))
 
 	var productFound entity.Product
 	err = db.First(&productFound, "id = ?", product.ID).Error
 
 	assert.Equal(t, product.ID, productFound.ID)
 	assert.Equal(t, "name updated", productFound.Name)
-	assert.Equal(t, 20, productFound.Price)
+	assert.Equal(t, entityPkg.MustParseMoney("20", "BRL"), productFound.Price)
+	assert.True(t, productFound.UpdatedAt.Equal(product.UpdatedAt))
+	assert.True(t, productFound.CreatedAt.Equal(createdAt))
+}
+
+func TestUpdateProductVersionConflict(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.Product{})
+	product, _ := entity.NewProduct("product test", entityPkg.MustParseMoney("10", "BRL"))
+	productDB := NewProduct(db)
+	assert.Nil(t, productDB.Create(product))
+
+	// Dois clientes leram a versão 1
+	first, _ := productDB.FindByID(product.ID.String())
+	second, _ := productDB.FindByID(product.ID.String())
+
+	first.Name = "first"
+	assert.Nil(t, productDB.Update(first))
+	assert.Equal(t, int64(2), first.Version)
+
+	// O segundo ainda está na versão 1 e não pode sobrescrever a alteração do primeiro
+	second.Name = "second"
+	assert.ErrorIs(t, productDB.Update(second), entity.ErrVersionConflict)
+	assert.Equal(t, int64(1), second.Version)
+
+	found, _ := productDB.FindByID(product.ID.String())
+	assert.Equal(t, "first", found.Name)
+	assert.Equal(t, int64(2), found.Version)
+	assert.True(t, product.CreatedAt.Equal(found.CreatedAt))
+}
+
+func TestDeleteProductVersionConflict(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.Product{})
+	product, _ := entity.NewProduct("product test", entityPkg.MustParseMoney("10", "BRL"))
+	productDB := NewProduct(db)
+	assert.Nil(t, productDB.Create(product))
+
+	// Alterado por outro cliente depois da leitura da versão 1
+	changed, _ := productDB.FindByID(product.ID.String())
+	changed.Name = "changed"
+	assert.Nil(t, productDB.Update(changed))
+
+	assert.ErrorIs(t, productDB.Delete(product.ID.String(), 1), entity.ErrVersionConflict)
+	found, err := productDB.FindByID(product.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, "changed", found.Name)
+
+	assert.NoError(t, productDB.Delete(product.ID.String(), changed.Version))
+	// Já está na lixeira
+	assert.ErrorIs(t, productDB.Delete(product.ID.String(), changed.Version+1), gorm.ErrRecordNotFound)
 }
 
 func TestUpdateProductProductNotFound(t *testing.T) {
@@ -77,7 +141,7 @@ func TestUpdateProductProductNotFound(t *testing.T) {
 	// Fazendo um migrate da tabela de usuário
 	db.AutoMigrate(&entity.Product{})
 	// Cria a nossa entity de product
-	product, _ := entity.NewProduct("product test", 10)
+	product, _ := entity.NewProduct("product test", entityPkg.MustParseMoney("10", "BRL"))
 	fmt.Println(product)
 	// Basicamente iniciamos a struct
 	productDB := NewProduct(db)
@@ -85,7 +149,7 @@ func TestUpdateProductProductNotFound(t *testing.T) {
 	assert.Nil(t, err)
 
 	product.Name = "name updated"
-	product.Price = 20
+	product.Price = entityPkg.MustParseMoney("20", "BRL")
 
 	err = productDB.Update(product)
 	assert.Error(t, err)
@@ -102,7 +166,7 @@ func TestFindByID(t *testing.T) {
 	// Fazendo um migrate da tabela de usuário
 	db.AutoMigrate(&entity.Product{})
 	// Cria a nossa entity de product
-	product, _ := entity.NewProduct("product test", 20)
+	product, _ := entity.NewProduct("product test", entityPkg.MustParseMoney("20", "BRL"))
 	// Basicamente iniciamos a struct
 	productDB := NewProduct(db)
 
@@ -127,13 +191,13 @@ func TestDelete(t *testing.T) {
 	// Fazendo um migrate da tabela de usuário
 	db.AutoMigrate(&entity.Product{})
 	// Cria a nossa entity de product
-	product, _ := entity.NewProduct("product test", 20)
+	product, _ := entity.NewProduct("product test", entityPkg.MustParseMoney("20", "BRL"))
 	// Basicamente iniciamos a struct
 	productDB := NewProduct(db)
 	err = productDB.Create(product)
 	assert.Nil(t, err)
 
-	err = productDB.Delete(product.ID.String())
+	err = productDB.Delete(product.ID.String(), product.Version)
 	assert.NoError(t, err)
 
 	product, err = productDB.FindByID(product.ID.String())
@@ -149,14 +213,14 @@ func TestFindAllProducts(t *testing.T) {
 	db.AutoMigrate(&entity.Product{})
 	// Cria a nossa entity de product
 	for i := 1; i < 24; i++ {
-		product, err := entity.NewProduct(fmt.Sprintf("Product %d", i), rand.Float64()*100)
+		product, err := entity.NewProduct(fmt.Sprintf("Product %d", i), entityPkg.Money{Amount: rand.Int63n(10000) + 1, Currency: "BRL"})
 		assert.NoError(t, err)
 		// Nesse caso estamos criando o produto direto no banco, sem passar pelo nossa struct e usando o nativo do GORM
 		db.Create(product)
 	}
 
 	productDB := NewProduct(db)
-	products, err := productDB.FindAll(1, 10, "asc")
+	products, err := productDB.FindAll(1, 10, nil, database.ProductFilter{})
 	assert.NoError(t, err)
 	// Verificando a paginação, se está retornando 10 registros na 1° pagina
 	assert.Len(t, products, 10)
@@ -165,7 +229,7 @@ func TestFindAllProducts(t *testing.T) {
 	assert.Equal(t, "Product 1", products[0].Name)
 	assert.Equal(t, "Product 10", products[9].Name)
 
-	products, err = productDB.FindAll(2, 10, "asc")
+	products, err = productDB.FindAll(2, 10, nil, database.ProductFilter{})
 	assert.NoError(t, err)
 	// Verificando a paginação, se está retornando 10 registros na 1° pagina
 	assert.Len(t, products, 10)
@@ -174,14 +238,536 @@ func TestFindAllProducts(t *testing.T) {
 	assert.Equal(t, "Product 11", products[0].Name)
 	assert.Equal(t, "Product 20", products[9].Name)
 
-	products, err = productDB.FindAll(1, 10, "desc")
+	products, err = productDB.FindAll(1, 10, []database.SortField{{Field: "created_at", Desc: true}}, database.ProductFilter{})
 	assert.NoError(t, err)
 
 	assert.Len(t, products, 10)
 	assert.Equal(t, "Product 23", products[0].Name)
 	assert.Equal(t, "Product 14", products[9].Name)
 
-	products, err = productDB.FindAll(1, 15, "desc")
+	products, err = productDB.FindAll(1, 15, []database.SortField{{Field: "created_at", Desc: true}}, database.ProductFilter{})
 	assert.NoError(t, err)
 	assert.Len(t, products, 15)
 }
+
+func TestFindAllProductsByOwner(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.Product{})
+
+	ownerID, otherID := entityPkg.NewID(), entityPkg.NewID()
+	for i, owner := range []*entityPkg.ID{&ownerID, &otherID, &ownerID, nil} {
+		product, _ := entity.NewProduct(fmt.Sprintf("Product %d", i), entityPkg.MustParseMoney("10", "BRL"))
+		product.OwnerID = owner
+		db.Create(product)
+	}
+
+	productDB := NewProduct(db)
+	products, err := productDB.FindAll(0, 0, nil, database.ProductFilter{OwnerID: ownerID.String()})
+	assert.NoError(t, err)
+	assert.Len(t, products, 2)
+	for _, p := range products {
+		assert.True(t, p.IsOwnedBy(ownerID.String()))
+	}
+
+	products, err = productDB.FindAll(0, 0, nil, database.ProductFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, products, 4)
+}
+
+func TestDeleteMovesProductToTrash(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.Product{})
+	productDB := NewProduct(db)
+
+	ownerID := entityPkg.NewID()
+	kept, _ := entity.NewProduct("kept", entityPkg.MustParseMoney("10", "BRL"))
+	deleted, _ := entity.NewProduct("deleted", entityPkg.MustParseMoney("10", "BRL"))
+	deleted.OwnerID = &ownerID
+	assert.NoError(t, productDB.Create(kept))
+	assert.NoError(t, productDB.Create(deleted))
+	assert.NoError(t, productDB.Delete(deleted.ID.String(), deleted.Version))
+
+	_, err = productDB.FindByID(deleted.ID.String())
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	products, err := productDB.FindAll(0, 0, nil, database.ProductFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+
+	trash, err := productDB.FindDeleted(0, 0, database.ProductFilter{OwnerID: ownerID.String()})
+	assert.NoError(t, err)
+	assert.Len(t, trash, 1)
+	assert.True(t, trash[0].DeletedAt.Valid)
+	trash, err = productDB.FindDeleted(0, 0, database.ProductFilter{OwnerID: entityPkg.NewID().String()})
+	assert.NoError(t, err)
+	assert.Empty(t, trash)
+	_, err = productDB.FindDeletedByID(kept.ID.String())
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestRestoreProduct(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.Product{})
+	productDB := NewProduct(db)
+
+	product, _ := entity.NewProduct("product", entityPkg.MustParseMoney("10", "BRL"))
+	assert.NoError(t, productDB.Create(product))
+	_, err = productDB.Restore(product.ID.String(), product.Version)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	// A remoção muda a versão e o updated_at, invalidando os ETags e o Last-Modified de antes
+	assert.NoError(t, productDB.Delete(product.ID.String(), product.Version))
+	deleted, err := productDB.FindDeletedByID(product.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), deleted.Version)
+	assert.True(t, deleted.UpdatedAt.After(product.UpdatedAt))
+
+	_, err = productDB.Restore(product.ID.String(), product.Version)
+	assert.ErrorIs(t, err, entity.ErrVersionConflict)
+	restored, err := productDB.Restore(product.ID.String(), deleted.Version)
+	assert.NoError(t, err)
+	assert.False(t, restored.DeletedAt.Valid)
+	assert.Equal(t, int64(3), restored.Version)
+
+	_, err = productDB.FindByID(product.ID.String())
+	assert.NoError(t, err)
+}
+
+func TestPurgeProduct(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.Product{})
+	productDB := NewProduct(db)
+
+	product, _ := entity.NewProduct("product", entityPkg.MustParseMoney("10", "BRL"))
+	assert.NoError(t, productDB.Create(product))
+	// Só apaga de vez o que já está na lixeira
+	assert.ErrorIs(t, productDB.Purge(product.ID.String()), gorm.ErrRecordNotFound)
+
+	assert.NoError(t, productDB.Delete(product.ID.String(), product.Version))
+	assert.NoError(t, productDB.Purge(product.ID.String()))
+	_, err = productDB.FindDeletedByID(product.ID.String())
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestPurgeDeletedBefore(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.Product{})
+	productDB := NewProduct(db)
+
+	old, _ := entity.NewProduct("old", entityPkg.MustParseMoney("10", "BRL"))
+	recent, _ := entity.NewProduct("recent", entityPkg.MustParseMoney("10", "BRL"))
+	assert.NoError(t, productDB.Create(old))
+	assert.NoError(t, productDB.Create(recent))
+	assert.NoError(t, productDB.Delete(old.ID.String(), old.Version))
+	assert.NoError(t, productDB.Delete(recent.ID.String(), recent.Version))
+	db.Unscoped().Model(old).Update("deleted_at", time.Now().Add(-48*time.Hour))
+
+	purged, err := productDB.PurgeDeletedBefore(time.Now().Add(-24 * time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	trash, err := productDB.FindDeleted(0, 0, database.ProductFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, trash, 1)
+	assert.Equal(t, recent.ID, trash[0].ID)
+}
+
+func TestStartPurgeWithoutInterval(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	productDB := NewProduct(db)
+
+	// Intervalo zero ou negativo usa o padrão em vez de derrubar o servidor
+	for _, interval := range []time.Duration{0, -time.Second} {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		assert.NotPanics(t, func() { productDB.StartPurge(ctx, interval, time.Hour) })
+		cancel()
+	}
+}
+
+func TestDuplicatedSKU(t *testing.T) {
+	// TranslateError igual ao database.Open, é ele que transforma a violação do índice em gorm.ErrDuplicatedKey
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{TranslateError: true})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.Product{})
+	productDB := NewProduct(db)
+
+	first, _ := entity.NewProduct("first", entityPkg.MustParseMoney("10", "BRL"), entity.WithSKU("SKU-1"))
+	second, _ := entity.NewProduct("second", entityPkg.MustParseMoney("10", "BRL"), entity.WithSKU("SKU-1"))
+	assert.NoError(t, productDB.Create(first))
+	assert.ErrorIs(t, productDB.Create(second), entity.ErrSKUAlreadyExists)
+
+	// Sem SKU não tem conflito
+	second.SKU = nil
+	assert.NoError(t, productDB.Create(second))
+	sku := "SKU-1"
+	second.SKU = &sku
+	assert.ErrorIs(t, productDB.Update(second), entity.ErrSKUAlreadyExists)
+}
+
+func TestFindAllProductsByCategory(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.Product{})
+	productDB := NewProduct(db)
+
+	// Roupas -> Camisetas -> Polo e Livros separado
+	clothes, _ := entity.NewCategory("Roupas", nil)
+	shirts, _ := entity.NewCategory("Camisetas", &clothes.ID)
+	polos, _ := entity.NewCategory("Polo", &shirts.ID)
+	books, _ := entity.NewCategory("Livros", nil)
+	for _, c := range []*entity.Category{clothes, shirts, polos, books} {
+		assert.NoError(t, db.Create(c).Error)
+	}
+	price := entityPkg.MustParseMoney("10", "BRL")
+	jacket, _ := entity.NewProduct("Jaqueta", price, entity.WithCategories(*clothes))
+	polo, _ := entity.NewProduct("Polo azul", price, entity.WithCategories(*polos, *books))
+	book, _ := entity.NewProduct("Livro", price, entity.WithCategories(*books))
+	for _, p := range []*entity.Product{jacket, polo, book} {
+		assert.NoError(t, productDB.Create(p))
+	}
+
+	products, err := productDB.FindAll(0, 0, nil, database.ProductFilter{CategoryID: clothes.ID.String()})
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+	assert.Equal(t, jacket.ID, products[0].ID)
+
+	products, err = productDB.FindAll(0, 0, nil, database.ProductFilter{CategoryID: clothes.ID.String(), IncludeSubcategories: true})
+	assert.NoError(t, err)
+	assert.Len(t, products, 2)
+
+	products, err = productDB.FindAll(0, 0, nil, database.ProductFilter{CategoryID: shirts.ID.String()})
+	assert.NoError(t, err)
+	assert.Empty(t, products)
+
+	found, err := productDB.FindByID(polo.ID.String())
+	assert.NoError(t, err)
+	assert.Len(t, found.Categories, 2)
+	// As categorias vêm em ordem alfabética
+	assert.Equal(t, "Livros", found.Categories[0].Name)
+}
+
+func TestSetProductCategories(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.Product{})
+	productDB := NewProduct(db)
+
+	a, _ := entity.NewCategory("A", nil)
+	b, _ := entity.NewCategory("B", nil)
+	assert.NoError(t, db.Create(a).Error)
+	assert.NoError(t, db.Create(b).Error)
+	product, _ := entity.NewProduct("product", entityPkg.MustParseMoney("10", "BRL"), entity.WithCategories(*a))
+	assert.NoError(t, productDB.Create(product))
+
+	stale := *product
+	assert.NoError(t, productDB.SetCategories(product, []entity.Category{*b}))
+	assert.Equal(t, int64(2), product.Version)
+	assert.ErrorIs(t, productDB.SetCategories(&stale, []entity.Category{*a}), entity.ErrVersionConflict)
+
+	found, err := productDB.FindByID(product.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), found.Version)
+	assert.Len(t, found.Categories, 1)
+	assert.Equal(t, b.ID, found.Categories[0].ID)
+
+	// Um Update comum não mexe nas categorias
+	found.Categories = nil
+	found.Name = "renamed"
+	assert.NoError(t, productDB.Update(found))
+	found, err = productDB.FindByID(product.ID.String())
+	assert.NoError(t, err)
+	assert.Len(t, found.Categories, 1)
+
+	assert.NoError(t, productDB.SetCategories(found, []entity.Category{}))
+	found, err = productDB.FindByID(product.ID.String())
+	assert.NoError(t, err)
+	assert.Empty(t, found.Categories)
+}
+
+func TestPurgeRemovesCategoryLinks(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.Product{})
+	productDB := NewProduct(db)
+
+	category, _ := entity.NewCategory("A", nil)
+	assert.NoError(t, db.Create(category).Error)
+	product, _ := entity.NewProduct("product", entityPkg.MustParseMoney("10", "BRL"), entity.WithCategories(*category))
+	assert.NoError(t, productDB.Create(product))
+	assert.NoError(t, productDB.Delete(product.ID.String(), product.Version))
+	assert.NoError(t, productDB.Purge(product.ID.String()))
+
+	var links int64
+	db.Table("product_categories").Count(&links)
+	assert.Zero(t, links)
+}
+
+func TestFindAllProductsByTags(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.Product{})
+	productDB := NewProduct(db)
+
+	price := entityPkg.MustParseMoney("10", "BRL")
+	both, _ := entity.NewProduct("both", price, entity.WithTags("Promoção", "verão"))
+	sale, _ := entity.NewProduct("sale", price, entity.WithTags("promoção", "inverno"))
+	none, _ := entity.NewProduct("none", price)
+	for _, p := range []*entity.Product{both, sale, none} {
+		assert.NoError(t, productDB.Create(p))
+	}
+
+	products, err := productDB.FindAll(0, 0, nil, database.ProductFilter{Tags: []string{"promoção", "verão"}})
+	assert.NoError(t, err)
+	assert.Len(t, products, 2)
+
+	products, err = productDB.FindAll(0, 0, nil, database.ProductFilter{Tags: []string{"promoção", "verão"}, MatchAllTags: true})
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+	assert.Equal(t, both.ID, products[0].ID)
+	// As tags vêm em ordem alfabética
+	assert.Equal(t, []entity.Tag{{Name: "promoção"}, {Name: "verão"}}, products[0].Tags)
+
+	products, err = productDB.FindAll(0, 0, nil, database.ProductFilter{Tags: []string{"outono"}})
+	assert.NoError(t, err)
+	assert.Empty(t, products)
+}
+
+func TestUpdateReplacesProductTags(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.Product{})
+	productDB := NewProduct(db)
+
+	product, _ := entity.NewProduct("product", entityPkg.MustParseMoney("10", "BRL"), entity.WithTags("a", "b"))
+	assert.NoError(t, productDB.Create(product))
+
+	product.Tags = entity.NewTags("b", "c")
+	assert.NoError(t, productDB.Update(product))
+	found, err := productDB.FindByID(product.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, []entity.Tag{{Name: "b"}, {Name: "c"}}, found.Tags)
+
+	found.Tags = nil
+	assert.NoError(t, productDB.Update(found))
+	found, err = productDB.FindByID(product.ID.String())
+	assert.NoError(t, err)
+	assert.Empty(t, found.Tags)
+}
+
+func TestFindAllProductsWithFilterExpression(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.Product{})
+	productDB := NewProduct(db)
+
+	chair, _ := entity.NewProduct("Office Chair", entityPkg.MustParseMoney("150.00", "BRL"), entity.WithStock(3), entity.WithStatus(entity.ProductStatusActive))
+	stool, _ := entity.NewProduct("Stool 50%", entityPkg.MustParseMoney("9.90", "BRL"), entity.WithStock(0))
+	yen, _ := entity.NewProduct("Chair", entityPkg.MustParseMoney("1500", "JPY"), entity.WithStock(10))
+	for _, p := range []*entity.Product{chair, stool, yen} {
+		assert.NoError(t, productDB.Create(p))
+	}
+	old := time.Now().AddDate(-1, 0, 0)
+	assert.NoError(t, db.Model(&entity.Product{}).Where("id = ?", stool.ID).Update("created_at", old).Error)
+
+	find := func(expression string) []string {
+		node, err := filter.Parse(expression)
+		assert.NoError(t, err, expression)
+		products, err := productDB.FindAll(0, 0, nil, database.ProductFilter{Expression: node})
+		assert.NoError(t, err, expression)
+		names := make([]string, len(products))
+		for i, p := range products {
+			names[i] = p.Name
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{"Office Chair", "Chair"}, find("name contains 'CHAIR'"))
+	// price compara na unidade de cada moeda: 1500 ienes passam de 200, mesmo com 200 reais sendo 20000 centavos
+	assert.Equal(t, []string{"Stool 50%", "Office Chair"}, find("price lt 200"))
+	assert.Equal(t, []string{"Office Chair"}, find("price gt 9.9 and price le 150"))
+	assert.Equal(t, []string{"Chair"}, find("price eq 1500 and currency eq jpy"))
+	assert.Equal(t, []string{"Stool 50%"}, find("name contains '%'"))
+	assert.Equal(t, []string{"Office Chair"}, find("status eq active or (stock gt 5 and not currency in (JPY))"))
+	assert.Equal(t, []string{"Stool 50%"}, find("created_at lt "+time.Now().AddDate(0, -1, 0).UTC().Format("2006-01-02")))
+	assert.Equal(t, []string{"Office Chair", "Chair"}, find("created_at ge "+time.Now().AddDate(0, -1, 0).UTC().Format("2006-01-02")))
+
+	node, _ := filter.Parse("owner_id eq 'x'")
+	_, err = productDB.FindAll(0, 0, nil, database.ProductFilter{Expression: node})
+	var filterErr *filter.Error
+	assert.ErrorAs(t, err, &filterErr)
+	assert.Equal(t, "owner_id", filterErr.Token)
+}
+
+func TestFindAllProductsSortedByFields(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.Product{})
+	productDB := NewProduct(db)
+
+	createdAt := time.Now()
+	for _, p := range []struct {
+		name  string
+		price string
+	}{{"b", "20"}, {"a", "20"}, {"c", "5"}, {"a", "5"}} {
+		product, _ := entity.NewProduct(p.name, entityPkg.MustParseMoney(p.price, "BRL"))
+		// Mesma data de cadastro em todos, a ordem só pode vir dos campos pedidos e do id
+		product.CreatedAt = createdAt
+		assert.NoError(t, productDB.Create(product))
+	}
+
+	sorted := func(sort []database.SortField) []string {
+		products, err := productDB.FindAll(0, 0, sort, database.ProductFilter{})
+		assert.NoError(t, err)
+		result := make([]string, len(products))
+		for i, p := range products {
+			result[i] = p.Name + " " + p.Price.Decimal()
+		}
+		return result
+	}
+	assert.Equal(t, []string{"a 20.00", "b 20.00", "a 5.00", "c 5.00"}, sorted([]database.SortField{{Field: "price", Desc: true}, {Field: "name"}}))
+	assert.Equal(t, []string{"a 5.00", "a 20.00", "b 20.00", "c 5.00"}, sorted([]database.SortField{{Field: "name"}, {Field: "price"}}))
+
+	// Sem campos para desempatar o id decide, então duas consultas iguais devolvem a mesma ordem
+	first := sorted([]database.SortField{{Field: "created_at"}})
+	assert.Equal(t, first, sorted(nil))
+	products, _ := productDB.FindAll(0, 0, nil, database.ProductFilter{})
+	for i := 1; i < len(products); i++ {
+		assert.Less(t, products[i-1].ID.String(), products[i].ID.String())
+	}
+
+	_, err = productDB.FindAll(0, 0, []database.SortField{{Field: "owner_id"}}, database.ProductFilter{})
+	assert.ErrorIs(t, err, database.ErrInvalidSort)
+}
+
+func TestFindPageWithCursors(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.Product{})
+	productDB := NewProduct(db)
+
+	// Preços repetidos para o nome e o id desempatarem
+	for i, price := range []string{"30", "10", "20", "10", "30", "20", "10"} {
+		product, _ := entity.NewProduct(fmt.Sprintf("product %d", i), entityPkg.MustParseMoney(price, "BRL"))
+		assert.NoError(t, productDB.Create(product))
+	}
+	sort := []database.SortField{{Field: "price", Desc: true}, {Field: "name"}}
+	all, err := productDB.FindAll(0, 0, sort, database.ProductFilter{})
+	assert.NoError(t, err)
+	names := func(products []entity.Product) []string {
+		result := make([]string, len(products))
+		for i, p := range products {
+			result[i] = p.Name
+		}
+		return result
+	}
+
+	// Avançando página a página passa por todos os produtos, na mesma ordem do FindAll
+	var walked []entity.Product
+	var after *database.Keyset
+	total := int64(7)
+	for {
+		products, info, err := productDB.FindPage(sort, database.CursorPage{Limit: 3, After: after, WithTotal: true}, database.ProductFilter{})
+		assert.NoError(t, err)
+		assert.Equal(t, total, info.Total)
+		assert.Equal(t, after != nil, info.HasPrev)
+		walked = append(walked, products...)
+		if !info.HasNext {
+			break
+		}
+		after = info.Last
+		// Um produto cadastrado no meio da navegação, antes da posição atual, não desloca as próximas páginas
+		if len(walked) == 3 {
+			extra, _ := entity.NewProduct("product 0", entityPkg.MustParseMoney("99", "BRL"))
+			assert.NoError(t, productDB.Create(extra))
+			total++
+		}
+	}
+	assert.Equal(t, names(all), names(walked))
+
+	// Voltando a partir da última página
+	last, info, err := productDB.FindPage(sort, database.CursorPage{Limit: 3, After: after}, database.ProductFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-1), info.Total)
+	previous, info, err := productDB.FindPage(sort, database.CursorPage{Limit: 3, Before: info.First}, database.ProductFilter{})
+	assert.NoError(t, err)
+	assert.True(t, info.HasNext)
+	assert.True(t, info.HasPrev)
+	assert.Equal(t, names(all[3:6]), names(previous))
+	assert.Equal(t, names(all[6:]), names(last))
+
+	_, _, err = productDB.FindPage(sort, database.CursorPage{Limit: 3, After: &database.Keyset{Values: []string{"10"}, ID: "x"}}, database.ProductFilter{})
+	assert.ErrorIs(t, err, database.ErrInvalidCursor)
+	_, _, err = productDB.FindPage(sort, database.CursorPage{Limit: 3, After: &database.Keyset{Values: []string{"ten", "a"}, ID: "x"}}, database.ProductFilter{})
+	assert.ErrorIs(t, err, database.ErrInvalidCursor)
+}
+
+func TestFindPageByDate(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.Product{})
+	productDB := NewProduct(db)
+
+	createdAt := time.Now()
+	for i := 0; i < 5; i++ {
+		product, _ := entity.NewProduct(fmt.Sprintf("product %d", i), entityPkg.MustParseMoney("10", "BRL"))
+		// Dois produtos com a mesma data para o cursor passar pelo desempate no id
+		product.CreatedAt = createdAt.Add(time.Duration(i/2) * time.Millisecond)
+		assert.NoError(t, productDB.Create(product))
+	}
+	sort := []database.SortField{{Field: "created_at", Desc: true}}
+	var seen []string
+	var after *database.Keyset
+	for {
+		products, info, err := productDB.FindPage(sort, database.CursorPage{Limit: 2, After: after}, database.ProductFilter{})
+		assert.NoError(t, err)
+		for _, p := range products {
+			seen = append(seen, p.ID.String())
+		}
+		if !info.HasNext {
+			break
+		}
+		after = info.Last
+	}
+	all, _ := productDB.FindAll(0, 0, sort, database.ProductFilter{})
+	assert.Len(t, seen, len(all))
+	for i, p := range all {
+		assert.Equal(t, p.ID.String(), seen[i])
+	}
+}
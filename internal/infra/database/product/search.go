@@ -0,0 +1,271 @@
+package database
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+
+	"github.com/waanvieira/api-users/internal/entity"
+	"github.com/waanvieira/api-users/internal/infra/database"
+	"gorm.io/gorm"
+)
+
+// Tabela FTS5 do sqlite com o nome e a descrição dos produtos, mantida em sincronia pelos triggers da migração
+const searchTable = "products_fts"
+
+// Quantidade de palavras do trecho da descrição devolvido na busca
+const snippetWords = 16
+
+// Marcadores do highlight e do snippet, trocados por <mark> depois de escapar o HTML do texto
+const (
+	markStart = "\x02"
+	markEnd   = "\x03"
+)
+
+// useFullText verifica uma única vez se a tabela do FTS5 existe, ela é criada pela migração 0015 apenas no sqlite
+// compilado com FTS5; sem o recurso ou sem a tabela a busca usa LIKE
+func (p *Product) useFullText() bool {
+	p.searchOnce.Do(func() {
+		p.fullText = database.HasFTS5(p.DB) && p.DB.Migrator().HasTable(searchTable)
+	})
+	return p.fullText
+}
+
+// searchHit é a linha encontrada pela busca, os produtos são carregados depois com as associações
+type searchHit struct {
+	ID          string
+	Score       float64
+	Highlight   string
+	Snippet     string
+	Name        string
+	Description string
+}
+
+// Search busca os termos no nome e na descrição dos produtos fora da lixeira, do mais para o menos relevante
+// Com FTS5 a relevância é o bm25 com o nome valendo 10 vezes a descrição, no LIKE é a soma dos termos
+// encontrados com o mesmo peso; o id desempata
+func (p *Product) Search(terms []database.SearchTerm, page, limit int) ([]entity.ProductSearchResult, int64, error) {
+	var hits []searchHit
+	var total int64
+	var err error
+	if p.useFullText() {
+		hits, total, err = p.searchFullText(terms, page, limit)
+	} else {
+		hits, total, err = p.searchLike(terms, page, limit)
+	}
+	if err != nil || len(hits) == 0 {
+		return nil, total, err
+	}
+
+	ids := make([]string, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+	var products []entity.Product
+	if err := withAssociations(p.DB).Where("id IN ?", ids).Find(&products).Error; err != nil {
+		return nil, 0, err
+	}
+	byID := make(map[string]entity.Product, len(products))
+	for _, product := range products {
+		byID[product.ID.String()] = product
+	}
+	results := make([]entity.ProductSearchResult, 0, len(hits))
+	for _, hit := range hits {
+		// Removido entre as duas consultas
+		product, ok := byID[hit.ID]
+		if !ok {
+			continue
+		}
+		results = append(results, entity.ProductSearchResult{
+			Product:   product,
+			Score:     hit.Score,
+			Highlight: markup(hit.Highlight),
+			Snippet:   markup(hit.Snippet),
+		})
+	}
+	return results, total, nil
+}
+
+func (p *Product) searchFullText(terms []database.SearchTerm, page, limit int) ([]searchHit, int64, error) {
+	match := func() *gorm.DB {
+		return p.DB.Table(searchTable).
+			Joins("JOIN products ON products.id = "+searchTable+".product_id").
+			Where(searchTable+" MATCH ?", ftsQuery(terms)).
+			Where("products.deleted_at IS NULL")
+	}
+	var total int64
+	if err := match().Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	// O bm25 é menor para os mais relevantes, o sinal é invertido para o score crescer com a relevância
+	query := match().Select(fmt.Sprintf(`products.id AS id,
+		-bm25(%[1]s, 0, 10, 1) AS score,
+		highlight(%[1]s, 1, char(2), char(3)) AS highlight,
+		snippet(%[1]s, 2, char(2), char(3), '…', %[2]d) AS snippet`, searchTable, snippetWords)).
+		Order("score DESC").Order("products.id")
+	var hits []searchHit
+	err := paginate(query, page, limit).Scan(&hits).Error
+	return hits, total, err
+}
+
+// ftsQuery monta a consulta do MATCH com cada termo entre aspas, assim o texto digitado
+// nunca é interpretado como operador do FTS5 (AND, NEAR, coluna:...)
+func ftsQuery(terms []database.SearchTerm) string {
+	parts := make([]string, len(terms))
+	for i, term := range terms {
+		parts[i] = `"` + strings.ReplaceAll(term.Text, `"`, `""`) + `"`
+		if term.Prefix {
+			parts[i] += "*"
+		}
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// searchLike é a busca dos bancos sem FTS5: cada termo precisa estar contido no nome ou na descrição
+// É uma aproximação, "cadeira" também encontra "cadeiras" e os acentos precisam ser iguais
+func (p *Product) searchLike(terms []database.SearchTerm, page, limit int) ([]searchHit, int64, error) {
+	query := p.DB.Model(&entity.Product{})
+	scores := make([]string, 0, len(terms)*2)
+	var scoreArgs []interface{}
+	for _, term := range terms {
+		pattern := "%" + escapeLike(term.Text) + "%"
+		query = query.Where("(LOWER(name) LIKE ? ESCAPE '!' OR LOWER(description) LIKE ? ESCAPE '!')", pattern, pattern)
+		scores = append(scores,
+			"CASE WHEN LOWER(name) LIKE ? ESCAPE '!' THEN 10 ELSE 0 END",
+			"CASE WHEN LOWER(description) LIKE ? ESCAPE '!' THEN 1 ELSE 0 END")
+		scoreArgs = append(scoreArgs, pattern, pattern)
+	}
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var hits []searchHit
+	query = query.Select("id, name, description, ("+strings.Join(scores, " + ")+") AS score", scoreArgs...).Order("score DESC").Order("id")
+	if err := paginate(query, page, limit).Scan(&hits).Error; err != nil {
+		return nil, 0, err
+	}
+	for i := range hits {
+		hits[i].Highlight = markTerms(hits[i].Name, terms)
+		hits[i].Snippet = snippet(hits[i].Description, terms)
+	}
+	return hits, total, nil
+}
+
+func paginate(query *gorm.DB, page, limit int) *gorm.DB {
+	if page > 0 && limit > 0 {
+		query = query.Limit(limit).Offset((page - 1) * limit)
+	}
+	return query
+}
+
+// escapeLike escapa os curingas do LIKE com !, o mesmo escape usado no ?filter=
+func escapeLike(value string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(value)
+}
+
+// markup escapa o HTML do texto e troca os marcadores por <mark>, o nome do produto pode ter qualquer caractere
+func markup(text string) string {
+	return strings.NewReplacer(markStart, "<mark>", markEnd, "</mark>").Replace(html.EscapeString(text))
+}
+
+// markTerms marca no texto as ocorrências dos termos, sem diferenciar maiúsculas e minúsculas
+func markTerms(text string, terms []database.SearchTerm) string {
+	runes := []rune(text)
+	return markRange(runes, matches(runes, terms), 0, len(runes))
+}
+
+// snippet devolve até snippetWords palavras da descrição a partir de um pouco antes do primeiro termo encontrado
+func snippet(text string, terms []database.SearchTerm) string {
+	runes := []rune(text)
+	marked := matches(runes, terms)
+	// Início e fim de cada palavra
+	var words [][2]int
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+		start := i
+		for i < len(runes) && !unicode.IsSpace(runes[i]) {
+			i++
+		}
+		words = append(words, [2]int{start, i})
+	}
+	if len(words) <= snippetWords {
+		return markRange(runes, marked, 0, len(runes))
+	}
+	first := 0
+	for i, word := range words {
+		if containsTrue(marked[word[0]:word[1]]) {
+			first = i
+			break
+		}
+	}
+	from := first - 3
+	if from < 0 {
+		from = 0
+	}
+	to := from + snippetWords
+	if to > len(words) {
+		to, from = len(words), len(words)-snippetWords
+	}
+	result := markRange(runes, marked, words[from][0], words[to-1][1])
+	if from > 0 {
+		result = "…" + result
+	}
+	if to < len(words) {
+		result += "…"
+	}
+	return result
+}
+
+// matches indica quais caracteres do texto fazem parte de algum termo
+func matches(runes []rune, terms []database.SearchTerm) []bool {
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+	marked := make([]bool, len(runes))
+	for _, term := range terms {
+		needle := []rune(term.Text)
+		for i := 0; i+len(needle) <= len(lower); i++ {
+			if string(lower[i:i+len(needle)]) == term.Text {
+				for j := i; j < i+len(needle); j++ {
+					marked[j] = true
+				}
+			}
+		}
+	}
+	return marked
+}
+
+// markRange devolve o trecho entre start e end com os marcadores em volta dos caracteres marcados
+func markRange(runes []rune, marked []bool, start, end int) string {
+	var b strings.Builder
+	open := false
+	for i := start; i < end; i++ {
+		if marked[i] != open {
+			if open {
+				b.WriteString(markEnd)
+			} else {
+				b.WriteString(markStart)
+			}
+			open = marked[i]
+		}
+		b.WriteRune(runes[i])
+	}
+	if open {
+		b.WriteString(markEnd)
+	}
+	return b.String()
+}
+
+func containsTrue(values []bool) bool {
+	for _, v := range values {
+		if v {
+			return true
+		}
+	}
+	return false
+}
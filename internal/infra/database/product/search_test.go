@@ -0,0 +1,142 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/waanvieira/api-users/internal/entity"
+	"github.com/waanvieira/api-users/internal/infra/database"
+	"github.com/waanvieira/api-users/internal/infra/database/migrations"
+	entityPkg "github.com/waanvieira/api-users/pkg/entity"
+
+	// Banco em memória sqlite
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func openSearchDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// newSearchDB cadastra os produtos da busca no banco que já tem o schema
+func newSearchDB(t *testing.T, db *gorm.DB) (*Product, map[string]*entity.Product) {
+	productDB := NewProduct(db)
+
+	price := entityPkg.MustParseMoney("10", "BRL")
+	products := map[string]*entity.Product{}
+	for name, description := range map[string]string{
+		"Cadeira de escritório":  "Encosto ergonômico e rodinhas",
+		"Mesa <gamer>":           "Acompanha cadeira de escritório",
+		"Luminária":              "Luz de leitura para a mesa do escritório, com braço articulado, cúpula de metal e lâmpada de led inclusa",
+		"Cadeira de praia velha": "Vai para a lixeira",
+	} {
+		product, _ := entity.NewProduct(name, price, entity.WithDescription(description))
+		assert.NoError(t, productDB.Create(product))
+		products[name] = product
+	}
+	old := products["Cadeira de praia velha"]
+	assert.NoError(t, productDB.Delete(old.ID.String(), old.Version))
+	return productDB, products
+}
+
+func search(t *testing.T, productDB *Product, q string) []entity.ProductSearchResult {
+	terms, err := database.ParseSearch(q)
+	assert.NoError(t, err)
+	results, total, err := productDB.Search(terms, 1, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(results)), total, q)
+	return results
+}
+
+func names(results []entity.ProductSearchResult) []string {
+	names := make([]string, len(results))
+	for i, r := range results {
+		names[i] = r.Product.Name
+	}
+	return names
+}
+
+func TestSearchProductsWithLike(t *testing.T) {
+	// Sem a tabela do FTS5 a busca usa LIKE
+	db := openSearchDB(t)
+	db.AutoMigrate(&entity.Product{})
+	productDB, _ := newSearchDB(t, db)
+
+	// O termo no nome vale mais que na descrição e os produtos da lixeira não aparecem
+	results := search(t, productDB, "cadeira")
+	assert.Equal(t, []string{"Cadeira de escritório", "Mesa <gamer>"}, names(results))
+	assert.Equal(t, "<mark>Cadeira</mark> de escritório", results[0].Highlight)
+	assert.Equal(t, "Mesa &lt;gamer&gt;", results[1].Highlight)
+	assert.Equal(t, "Acompanha <mark>cadeira</mark> de escritório", results[1].Snippet)
+	assert.Greater(t, results[0].Score, results[1].Score)
+
+	assert.Equal(t, []string{"Mesa <gamer>"}, names(search(t, productDB, `"cadeira de escritório" gamer`)))
+	assert.Empty(t, search(t, productDB, "sofá"))
+
+	// A descrição longa vem cortada em volta do termo
+	results = search(t, productDB, "led")
+	assert.Equal(t, "…para a mesa do escritório, com braço articulado, cúpula de metal e lâmpada de <mark>led</mark> inclusa", results[0].Snippet)
+
+	// Paginação
+	terms, _ := database.ParseSearch("escritório")
+	results, total, err := productDB.Search(terms, 2, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	assert.Len(t, results, 1)
+}
+
+func TestSearchProductsWithFullText(t *testing.T) {
+	db := openSearchDB(t)
+	if !database.HasFTS5(db) {
+		t.Skip("sqlite without FTS5, run make test (go test -tags sqlite_fts5)")
+	}
+	// Os produtos são cadastrados antes da migração do índice, que indexa os que já existem
+	migrator, err := migrations.NewMigrator(db)
+	assert.NoError(t, err)
+	fts := migrator.Migrations[len(migrator.Migrations)-1]
+	assert.Equal(t, "create_products_fts", fts.Name)
+	migrator.Migrations = migrator.Migrations[:len(migrator.Migrations)-1]
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+	productDB, products := newSearchDB(t, db)
+	migrator.Migrations = append(migrator.Migrations, fts)
+	_, err = migrator.Up()
+	assert.NoError(t, err)
+
+	results := search(t, productDB, "cadeira")
+	assert.Equal(t, []string{"Cadeira de escritório", "Mesa <gamer>"}, names(results))
+	assert.Equal(t, "<mark>Cadeira</mark> de escritório", results[0].Highlight)
+	assert.Equal(t, "Mesa &lt;gamer&gt;", results[1].Highlight)
+	assert.Equal(t, "Acompanha <mark>cadeira</mark> de escritório", results[1].Snippet)
+
+	// Prefixo, frase e acentos
+	assert.Equal(t, []string{"Cadeira de escritório"}, names(search(t, productDB, "ergo*")))
+	assert.Empty(t, search(t, productDB, "ergo"))
+	assert.Equal(t, []string{"Mesa <gamer>"}, names(search(t, productDB, `"acompanha cadeira"`)))
+	assert.Empty(t, search(t, productDB, `"cadeira acompanha"`))
+	assert.Len(t, search(t, productDB, "escritorio"), 3)
+	// Operadores do FTS5 digitados na busca são apenas texto
+	assert.Empty(t, search(t, productDB, "name:mesa OR NEAR(cadeira)"))
+
+	// Os triggers mantêm o índice em sincronia
+	mesa := products["Mesa <gamer>"]
+	mesa.Name = "Escrivaninha"
+	assert.NoError(t, productDB.Update(mesa))
+	assert.Equal(t, []string{"Escrivaninha"}, names(search(t, productDB, "escrivaninha")))
+	assert.Empty(t, search(t, productDB, "gamer"))
+	assert.NoError(t, productDB.Delete(mesa.ID.String(), mesa.Version))
+	assert.NoError(t, productDB.Purge(mesa.ID.String()))
+	var indexed int64
+	db.Table(searchTable).Where("product_id = ?", mesa.ID.String()).Count(&indexed)
+	assert.Zero(t, indexed)
+
+	// O down remove o índice e a busca volta para o LIKE
+	_, err = migrator.Down(1)
+	assert.NoError(t, err)
+	assert.False(t, db.Migrator().HasTable(searchTable))
+	assert.Equal(t, []string{"Cadeira de escritório"}, names(search(t, NewProduct(db), "ergonômico")))
+}
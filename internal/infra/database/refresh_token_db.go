@@ -0,0 +1,64 @@
+package database
+
+import (
+	"time"
+
+	"github.com/waanvieira/api-users/internal/entity"
+	"gorm.io/gorm"
+)
+
+type RefreshToken struct {
+	DB *gorm.DB
+}
+
+func NewRefreshToken(db *gorm.DB) *RefreshToken {
+	return &RefreshToken{DB: db}
+}
+
+func (t *RefreshToken) Create(token *entity.RefreshToken) error {
+	return t.DB.Create(token).Error
+}
+
+// Buscamos sempre pelo hash, o token em texto puro nunca chega no banco
+func (t *RefreshToken) FindByHash(hash string) (*entity.RefreshToken, error) {
+	var token entity.RefreshToken
+	if err := t.DB.Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// Rotate marca o token atual como trocado e salva o próximo da mesma família numa única transação
+// O update só acontece se o token ainda não foi usado, assim duas requisições ao mesmo tempo com o mesmo token
+// não conseguem gerar dois tokens novos, a segunda recebe ErrRefreshTokenReused
+func (t *RefreshToken) Rotate(current *entity.RefreshToken, next *entity.RefreshToken) error {
+	return t.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&entity.RefreshToken{}).
+			Where("id = ? AND rotated_at IS NULL AND revoked_at IS NULL", current.ID).
+			Update("rotated_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return entity.ErrRefreshTokenReused
+		}
+		current.RotatedAt = &now
+		return tx.Create(next).Error
+	})
+}
+
+// RevokeFamily revoga todos os tokens ainda válidos gerados a partir do mesmo login
+func (t *RefreshToken) RevokeFamily(familyID string) error {
+	return t.DB.Model(&entity.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeUser revoga os tokens ainda válidos de todos os logins do usuário, ex: troca de senha ou conta removida
+func (t *RefreshToken) RevokeUser(userID string) error {
+	return t.DB.Model(&entity.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
@@ -0,0 +1,113 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/waanvieira/api-users/internal/entity"
+	entityPkg "github.com/waanvieira/api-users/pkg/entity"
+
+	// Banco em memória sqlite
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func newRefreshTokenDB(t *testing.T) (*gorm.DB, *RefreshToken) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.RefreshToken{})
+	return db, NewRefreshToken(db)
+}
+
+func TestCreateAndFindRefreshTokenByHash(t *testing.T) {
+	_, tokenDB := newRefreshTokenDB(t)
+	token, raw, err := entity.NewRefreshToken(entityPkg.NewID(), entityPkg.NewID(), time.Hour)
+	assert.Nil(t, err)
+
+	err = tokenDB.Create(token)
+	assert.Nil(t, err)
+
+	found, err := tokenDB.FindByHash(entity.HashRefreshToken(raw))
+	assert.Nil(t, err)
+	assert.Equal(t, token.ID, found.ID)
+	assert.Equal(t, token.FamilyID, found.FamilyID)
+
+	_, err = tokenDB.FindByHash(entity.HashRefreshToken("unknown"))
+	assert.Error(t, err)
+}
+
+func TestRotateRefreshToken(t *testing.T) {
+	_, tokenDB := newRefreshTokenDB(t)
+	userID, familyID := entityPkg.NewID(), entityPkg.NewID()
+	current, _, _ := entity.NewRefreshToken(userID, familyID, time.Hour)
+	assert.Nil(t, tokenDB.Create(current))
+
+	next, nextRaw, _ := entity.NewRefreshToken(userID, familyID, time.Hour)
+	err := tokenDB.Rotate(current, next)
+	assert.Nil(t, err)
+	assert.NotNil(t, current.RotatedAt)
+
+	rotated, err := tokenDB.FindByHash(current.TokenHash)
+	assert.Nil(t, err)
+	assert.True(t, rotated.IsUsed())
+
+	saved, err := tokenDB.FindByHash(entity.HashRefreshToken(nextRaw))
+	assert.Nil(t, err)
+	assert.False(t, saved.IsUsed())
+
+	// Usar o mesmo token de novo não pode gerar outro token
+	other, otherRaw, _ := entity.NewRefreshToken(userID, familyID, time.Hour)
+	err = tokenDB.Rotate(rotated, other)
+	assert.ErrorIs(t, err, entity.ErrRefreshTokenReused)
+	_, err = tokenDB.FindByHash(entity.HashRefreshToken(otherRaw))
+	assert.Error(t, err)
+}
+
+func TestRevokeRefreshTokenFamily(t *testing.T) {
+	_, tokenDB := newRefreshTokenDB(t)
+	userID, familyID := entityPkg.NewID(), entityPkg.NewID()
+	first, _, _ := entity.NewRefreshToken(userID, familyID, time.Hour)
+	second, _, _ := entity.NewRefreshToken(userID, familyID, time.Hour)
+	otherFamily, _, _ := entity.NewRefreshToken(userID, entityPkg.NewID(), time.Hour)
+	assert.Nil(t, tokenDB.Create(first))
+	assert.Nil(t, tokenDB.Create(second))
+	assert.Nil(t, tokenDB.Create(otherFamily))
+
+	err := tokenDB.RevokeFamily(familyID.String())
+	assert.Nil(t, err)
+
+	for _, token := range []*entity.RefreshToken{first, second} {
+		found, err := tokenDB.FindByHash(token.TokenHash)
+		assert.Nil(t, err)
+		assert.NotNil(t, found.RevokedAt)
+	}
+	found, err := tokenDB.FindByHash(otherFamily.TokenHash)
+	assert.Nil(t, err)
+	assert.Nil(t, found.RevokedAt)
+}
+
+func TestRevokeRefreshTokensOfUser(t *testing.T) {
+	_, tokenDB := newRefreshTokenDB(t)
+	userID := entityPkg.NewID()
+	first, _, _ := entity.NewRefreshToken(userID, entityPkg.NewID(), time.Hour)
+	second, _, _ := entity.NewRefreshToken(userID, entityPkg.NewID(), time.Hour)
+	otherUser, _, _ := entity.NewRefreshToken(entityPkg.NewID(), entityPkg.NewID(), time.Hour)
+	assert.Nil(t, tokenDB.Create(first))
+	assert.Nil(t, tokenDB.Create(second))
+	assert.Nil(t, tokenDB.Create(otherUser))
+
+	assert.Nil(t, tokenDB.RevokeUser(userID.String()))
+
+	// Todas as famílias do usuário, as dos outros usuários continuam valendo
+	for _, token := range []*entity.RefreshToken{first, second} {
+		found, err := tokenDB.FindByHash(token.TokenHash)
+		assert.Nil(t, err)
+		assert.NotNil(t, found.RevokedAt)
+	}
+	found, err := tokenDB.FindByHash(otherUser.TokenHash)
+	assert.Nil(t, err)
+	assert.Nil(t, found.RevokedAt)
+}
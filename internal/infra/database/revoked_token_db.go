@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/waanvieira/api-users/internal/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RevokedToken é a lista de tokens revogados, salva no banco e com um cache em memória
+// O cache guarda apenas os tokens revogados, assim um token revogado não precisa ir no banco a cada requisição
+type RevokedToken struct {
+	DB    *gorm.DB
+	mu    sync.RWMutex
+	cache map[string]time.Time
+}
+
+func NewRevokedToken(db *gorm.DB) *RevokedToken {
+	return &RevokedToken{DB: db, cache: map[string]time.Time{}}
+}
+
+// Revoke salva o jti até o horário em que o token expiraria, revogar duas vezes o mesmo token não é erro
+func (t *RevokedToken) Revoke(jti string, expiresAt time.Time) error {
+	err := t.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(entity.NewRevokedToken(jti, expiresAt)).Error
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	t.cache[jti] = expiresAt
+	t.mu.Unlock()
+	return nil
+}
+
+// IsRevoked olha primeiro no cache e depois no banco, o banco é necessário porque outra instância da api pode ter revogado o token
+func (t *RevokedToken) IsRevoked(jti string) (bool, error) {
+	now := time.Now()
+	t.mu.RLock()
+	expiresAt, ok := t.cache[jti]
+	t.mu.RUnlock()
+	if ok {
+		return now.Before(expiresAt), nil
+	}
+
+	var revoked entity.RevokedToken
+	err := t.DB.Where("jti = ? AND expires_at > ?", jti, now).Limit(1).Find(&revoked).Error
+	if err != nil {
+		return false, err
+	}
+	if revoked.JTI == "" {
+		return false, nil
+	}
+	t.mu.Lock()
+	t.cache[jti] = revoked.ExpiresAt
+	t.mu.Unlock()
+	return true, nil
+}
+
+// PurgeExpired apaga do banco e do cache os tokens que já expiraram
+func (t *RevokedToken) PurgeExpired() (int64, error) {
+	now := time.Now()
+	result := t.DB.Where("expires_at <= ?", now).Delete(&entity.RevokedToken{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	t.mu.Lock()
+	for jti, expiresAt := range t.cache {
+		if !now.Before(expiresAt) {
+			delete(t.cache, jti)
+		}
+	}
+	t.mu.Unlock()
+	return result.RowsAffected, nil
+}
+
+// Intervalo da limpeza quando o informado não é positivo, o mesmo padrão do JWT_REVOCATION_PURGE_INTERVAL
+const defaultRevocationPurgeInterval = 10 * time.Minute
+
+// StartPurge executa o PurgeExpired a cada intervalo até o contexto ser cancelado
+func (t *RevokedToken) StartPurge(ctx context.Context, interval time.Duration) {
+	// O time.NewTicker entra em pânico com intervalo zero ou negativo
+	if interval <= 0 {
+		log.Printf("purge revoked tokens: invalid interval %s, using %s", interval, defaultRevocationPurgeInterval)
+		interval = defaultRevocationPurgeInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := t.PurgeExpired(); err != nil {
+				log.Printf("purge revoked tokens: %v", err)
+			}
+		}
+	}
+}
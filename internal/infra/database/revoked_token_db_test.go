@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/waanvieira/api-users/internal/entity"
+
+	// Banco em memória sqlite
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func newRevokedTokenDB(t *testing.T) (*gorm.DB, *RevokedToken) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.RevokedToken{})
+	return db, NewRevokedToken(db)
+}
+
+func TestRevokeToken(t *testing.T) {
+	db, revokedDB := newRevokedTokenDB(t)
+
+	revoked, err := revokedDB.IsRevoked("jti-1")
+	assert.Nil(t, err)
+	assert.False(t, revoked)
+
+	err = revokedDB.Revoke("jti-1", time.Now().Add(time.Hour))
+	assert.Nil(t, err)
+	// Revogar de novo o mesmo token não é erro
+	err = revokedDB.Revoke("jti-1", time.Now().Add(time.Hour))
+	assert.Nil(t, err)
+
+	revoked, err = revokedDB.IsRevoked("jti-1")
+	assert.Nil(t, err)
+	assert.True(t, revoked)
+
+	var count int64
+	db.Model(&entity.RevokedToken{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestIsRevokedReadsFromDatabase(t *testing.T) {
+	db, revokedDB := newRevokedTokenDB(t)
+	// Simulando outra instância da api revogando o token direto no banco
+	db.Create(entity.NewRevokedToken("jti-other", time.Now().Add(time.Hour)))
+
+	revoked, err := revokedDB.IsRevoked("jti-other")
+	assert.Nil(t, err)
+	assert.True(t, revoked)
+}
+
+func TestPurgeExpiredRevokedTokens(t *testing.T) {
+	db, revokedDB := newRevokedTokenDB(t)
+	assert.Nil(t, revokedDB.Revoke("expired", time.Now().Add(-time.Minute)))
+	assert.Nil(t, revokedDB.Revoke("valid", time.Now().Add(time.Hour)))
+
+	// Token expirado não é mais considerado revogado, o exp do JWT já barra ele
+	revoked, err := revokedDB.IsRevoked("expired")
+	assert.Nil(t, err)
+	assert.False(t, revoked)
+
+	purged, err := revokedDB.PurgeExpired()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	var count int64
+	db.Model(&entity.RevokedToken{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+	revoked, err = revokedDB.IsRevoked("valid")
+	assert.Nil(t, err)
+	assert.True(t, revoked)
+}
+
+func TestStartPurgeRevokedTokensWithoutInterval(t *testing.T) {
+	_, revokedDB := newRevokedTokenDB(t)
+
+	// Intervalo zero ou negativo usa o padrão em vez de derrubar o servidor
+	for _, interval := range []time.Duration{0, -time.Second} {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		assert.NotPanics(t, func() { revokedDB.StartPurge(ctx, interval) })
+		cancel()
+	}
+}
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Limites do ?q= da busca
+const (
+	MaxSearchLength = 200
+	MaxSearchTerms  = 10
+)
+
+var ErrInvalidSearch = errors.New("invalid search")
+
+// SearchTerm é um termo da busca, todos os termos precisam ser encontrados no nome ou na descrição
+type SearchTerm struct {
+	// Em minúsculas e com um único espaço entre as palavras
+	Text string
+	// Veio entre aspas, as palavras precisam aparecer juntas e nessa ordem
+	Phrase bool
+	// Terminado em *, também encontra as palavras que começam com o termo
+	Prefix bool
+}
+
+// ParseSearch lê o ?q= no formato: cadeira "escritório gamer" ergo*
+// Palavras soltas precisam aparecer em qualquer ordem, o texto entre aspas é uma frase e o * no final busca pelo prefixo
+// Uma aspa sem fechamento vale até o fim do texto; termos sem letras nem números são ignorados
+func ParseSearch(q string) ([]SearchTerm, error) {
+	if utf8.RuneCountInString(q) > MaxSearchLength {
+		return nil, fmt.Errorf("%w: q has more than %d characters", ErrInvalidSearch, MaxSearchLength)
+	}
+	var terms []SearchTerm
+	runes := []rune(q)
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+		var term SearchTerm
+		start := i
+		if runes[i] == '"' {
+			term.Phrase = true
+			start = i + 1
+			i = start
+			for i < len(runes) && runes[i] != '"' {
+				i++
+			}
+			term.Text = string(runes[start:i])
+			// Pula a aspa de fechamento
+			if i < len(runes) {
+				i++
+			}
+			if i < len(runes) && runes[i] == '*' {
+				term.Prefix = true
+				i++
+			}
+		} else {
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '"' {
+				i++
+			}
+			term.Text = string(runes[start:i])
+			term.Prefix = strings.HasSuffix(term.Text, "*")
+			term.Text = strings.TrimRight(term.Text, "*")
+		}
+		term.Text = strings.ToLower(strings.Join(strings.Fields(term.Text), " "))
+		if !strings.ContainsFunc(term.Text, isWordRune) {
+			continue
+		}
+		terms = append(terms, term)
+	}
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("%w: q is required", ErrInvalidSearch)
+	}
+	if len(terms) > MaxSearchTerms {
+		return nil, fmt.Errorf("%w: at most %d terms", ErrInvalidSearch, MaxSearchTerms)
+	}
+	return terms, nil
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
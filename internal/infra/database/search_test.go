@@ -0,0 +1,30 @@
+package database
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSearch(t *testing.T) {
+	cases := map[string][]SearchTerm{
+		"cadeira":                  {{Text: "cadeira"}},
+		"  Cadeira   Gamer ":       {{Text: "cadeira"}, {Text: "gamer"}},
+		"ergo*":                    {{Text: "ergo", Prefix: true}},
+		`"Cadeira  de escritório"`: {{Text: "cadeira de escritório", Phrase: true}},
+		`mesa "pé de"* -`:          {{Text: "mesa"}, {Text: "pé de", Phrase: true, Prefix: true}},
+		`"sem fechar`:              {{Text: "sem fechar", Phrase: true}},
+		`a"b"`:                     {{Text: "a"}, {Text: "b", Phrase: true}},
+	}
+	for input, expected := range cases {
+		terms, err := ParseSearch(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, expected, terms, input)
+	}
+
+	for _, invalid := range []string{"", "   ", `"" * -`, strings.Repeat("a", MaxSearchLength+1), strings.Repeat("a ", MaxSearchTerms+1)} {
+		_, err := ParseSearch(invalid)
+		assert.ErrorIs(t, err, ErrInvalidSearch, invalid)
+	}
+}
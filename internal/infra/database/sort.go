@@ -0,0 +1,57 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Quantidade máxima de campos no ?sort=
+const MaxSortFields = 5
+
+var ErrInvalidSort = errors.New("invalid sort")
+
+var sortFieldPattern = regexp.MustCompile(`^[a-z_]+$`)
+
+// SortField é uma chave da ordenação, Desc inverte a ordem do campo
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// ParseSort lê o ?sort= no formato "-price,name": o "-" ordena do maior para o menor
+// Os valores antigos "asc" e "desc" continuam valendo e ordenam por created_at
+// Os campos aceitos são verificados pelo repositório de cada entidade
+func ParseSort(s string) ([]SortField, error) {
+	s = strings.TrimSpace(s)
+	switch s {
+	case "":
+		return nil, nil
+	case "asc":
+		return []SortField{{Field: "created_at"}}, nil
+	case "desc":
+		return []SortField{{Field: "created_at", Desc: true}}, nil
+	}
+	keys := strings.Split(s, ",")
+	if len(keys) > MaxSortFields {
+		return nil, fmt.Errorf("%w: at most %d fields", ErrInvalidSort, MaxSortFields)
+	}
+	fields := make([]SortField, 0, len(keys))
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		field := SortField{Field: strings.TrimPrefix(strings.TrimPrefix(key, "-"), "+")}
+		field.Desc = strings.HasPrefix(key, "-")
+		field.Field = strings.ToLower(field.Field)
+		if !sortFieldPattern.MatchString(field.Field) {
+			return nil, fmt.Errorf("%w: %q is not a field name", ErrInvalidSort, key)
+		}
+		if seen[field.Field] {
+			return nil, fmt.Errorf("%w: %s is repeated", ErrInvalidSort, field.Field)
+		}
+		seen[field.Field] = true
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
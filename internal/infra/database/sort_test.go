@@ -0,0 +1,27 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSort(t *testing.T) {
+	cases := map[string][]SortField{
+		"":               nil,
+		"asc":            {{Field: "created_at"}},
+		"desc":           {{Field: "created_at", Desc: true}},
+		"-price,name":    {{Field: "price", Desc: true}, {Field: "name"}},
+		" +Stock , -sku": {{Field: "stock"}, {Field: "sku", Desc: true}},
+	}
+	for input, expected := range cases {
+		fields, err := ParseSort(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, expected, fields, input)
+	}
+
+	for _, invalid := range []string{"price,", "--price", "name;drop", "name,-name", "a,b,c,d,e,f"} {
+		_, err := ParseSort(invalid)
+		assert.ErrorIs(t, err, ErrInvalidSort, invalid)
+	}
+}
@@ -0,0 +1,27 @@
+package database
+
+import (
+	"github.com/waanvieira/api-users/internal/entity"
+	"gorm.io/gorm"
+)
+
+type Tag struct {
+	DB *gorm.DB
+}
+
+func NewTag(db *gorm.DB) *Tag {
+	return &Tag{DB: db}
+}
+
+// FindAllWithCount devolve as tags em uso com a quantidade de produtos, as mais usadas primeiro
+// Produtos da lixeira não contam e tags sem nenhum produto não aparecem
+func (t *Tag) FindAllWithCount() ([]entity.TagCount, error) {
+	tags := []entity.TagCount{}
+	err := t.DB.Table("product_tags").
+		Select("product_tags.tag_name AS name, COUNT(*) AS count").
+		Joins("JOIN products ON products.id = product_tags.product_id AND products.deleted_at IS NULL").
+		Group("product_tags.tag_name").
+		Order("count DESC").Order("name").
+		Scan(&tags).Error
+	return tags, err
+}
@@ -0,0 +1,36 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/waanvieira/api-users/internal/entity"
+	databaseProduct "github.com/waanvieira/api-users/internal/infra/database/product"
+	entityPkg "github.com/waanvieira/api-users/pkg/entity"
+
+	// Banco em memória sqlite
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestFindAllTagsWithCount(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.AutoMigrate(&entity.Product{})
+	productDB := databaseProduct.NewProduct(db)
+
+	price := entityPkg.MustParseMoney("10", "BRL")
+	a, _ := entity.NewProduct("a", price, entity.WithTags("Verão", "promoção"))
+	b, _ := entity.NewProduct("b", price, entity.WithTags(" PROMOÇÃO "))
+	deleted, _ := entity.NewProduct("deleted", price, entity.WithTags("inverno"))
+	for _, p := range []*entity.Product{a, b, deleted} {
+		assert.NoError(t, productDB.Create(p))
+	}
+	assert.NoError(t, productDB.Delete(deleted.ID.String(), deleted.Version))
+
+	tags, err := NewTag(db).FindAllWithCount()
+	assert.NoError(t, err)
+	assert.Equal(t, []entity.TagCount{{Name: "promoção", Count: 2}, {Name: "verão", Count: 1}}, tags)
+}
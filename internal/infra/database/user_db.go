@@ -1,7 +1,10 @@
 package database
 
 import (
+	"errors"
+
 	"github.com/waanvieira/api-users/internal/entity"
+	entityPkg "github.com/waanvieira/api-users/pkg/entity"
 	"gorm.io/gorm"
 )
 
@@ -17,18 +20,96 @@ func NewUser(db *gorm.DB) *User {
 
 // Não retorna a nossa entity, retorna apenas um erro, então em algum lugar podemos chamar essa função e verifica apenas se tem um erro
 func (u *User) Create(user *entity.User) error {
-	return u.DB.Create(user).Error
+	return translateUserError(u.DB.Create(user).Error)
 }
 
 // (u *User) - indica que a função é dessa nossa struct
 // (email string) Nossao paramaetro que é uma string
 // (*entity.User, error) - Significa que retorna um ponteiro de User da nossa entity ou retorna um erro
 func (u *User) FindByEmail(email string) (*entity.User, error) {
+	// O email é salvo normalizado, então a busca usa a mesma normalização, ex: " User@Dev.com" encontra "user@dev.com"
+	address, err := entityPkg.NewEmailAddress(email)
+	if err != nil {
+		return nil, gorm.ErrRecordNotFound
+	}
 	var user entity.User
 	// Os dados são preenchidos no Firs(&user), significa que não deu nenhum erro e vai hidratar o nosso ponteiro
-	if err := u.DB.Where("email = ?", email).First(&user).Error; err != nil {
+	if err := u.DB.Where(emailCondition(u.DB), address.String()).First(&user).Error; err != nil {
 		return nil, err
 	}
 
 	return &user, nil
 }
+
+// emailCondition compara pela mesma expressão do índice único da migração 0007, senão o banco não usa o índice
+// No sqlite e no postgres o índice é em LOWER(email), no MySQL é na própria coluna (o collation já ignora maiúsculas)
+func emailCondition(db *gorm.DB) string {
+	if db.Dialector.Name() == "mysql" {
+		return "email = ?"
+	}
+	return "LOWER(email) = ?"
+}
+
+func (u *User) FindByID(id string) (*entity.User, error) {
+	var user entity.User
+	if err := u.DB.Where("id = ?", id).First(&user).Error; err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// FindAll lista os usuários ordenados pelo nome, com a mesma paginação da listagem de produtos
+func (u *User) FindAll(page int, limit int, sort string) ([]entity.User, error) {
+	var users []entity.User
+	if sort != "asc" && sort != "desc" {
+		sort = "asc"
+	}
+	query := u.DB.Order("name " + sort).Order("id")
+	if page != 0 && limit != 0 {
+		query = query.Limit(limit).Offset((page - 1) * limit)
+	}
+	err := query.Find(&users).Error
+	return users, err
+}
+
+func (u *User) Update(user *entity.User) error {
+	// Verifica se o registro existe, o Save sozinho criaria o usuário caso não exista
+	_, err := u.FindByID(user.ID.String())
+	if err != nil {
+		return err
+	}
+	return translateUserError(u.DB.Save(user).Error)
+}
+
+func (u *User) Delete(id string) error {
+	user, err := u.FindByID(id)
+	if err != nil {
+		return err
+	}
+	return u.DB.Delete(user).Error
+}
+
+// SetRole altera apenas o perfil do usuário, usado pelo comando "server user role"
+func (u *User) SetRole(id string, role entity.Role) error {
+	if !role.IsValid() {
+		return entity.ErrInvalidRole
+	}
+	result := u.DB.Model(&entity.User{}).Where("id = ?", id).Update("role", role)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// translateUserError troca a violação do índice único de email pelo erro do domínio
+// Assim duas requisições cadastrando o mesmo email ao mesmo tempo resultam em 409 e não em um usuário duplicado
+func translateUserError(err error) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return entity.ErrEmailAlreadyRegistered
+	}
+	return err
+}
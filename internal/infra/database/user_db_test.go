@@ -1,6 +1,7 @@
 package database
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -61,3 +62,129 @@ func TestFindByEmail(t *testing.T) {
 	assert.Equal(t, user.Name, userByEmail.Name)
 	assert.Equal(t, user.Email, userByEmail.Email)
 }
+
+func TestFindUserByIDAndSetRole(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.User{})
+	user, _ := entity.NewUser("user test", "user@teste.com", "123456")
+	userDB := NewUser(db)
+	assert.Nil(t, userDB.Create(user))
+
+	userByID, err := userDB.FindByID(user.ID.String())
+	assert.Nil(t, err)
+	assert.Equal(t, user.Email, userByID.Email)
+	assert.Equal(t, entity.RoleViewer, userByID.Role)
+
+	err = userDB.SetRole(user.ID.String(), entity.RoleAdmin)
+	assert.Nil(t, err)
+	userByID, _ = userDB.FindByID(user.ID.String())
+	assert.Equal(t, entity.RoleAdmin, userByID.Role)
+
+	assert.Equal(t, entity.ErrInvalidRole, userDB.SetRole(user.ID.String(), "root"))
+	assert.Equal(t, gorm.ErrRecordNotFound, userDB.SetRole("unknown", entity.RoleAdmin))
+}
+
+func TestFindAllUsers(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.User{})
+	userDB := NewUser(db)
+	for i := 1; i <= 12; i++ {
+		user, _ := entity.NewUser(fmt.Sprintf("User %02d", i), fmt.Sprintf("user%d@teste.com", i), "123456")
+		assert.Nil(t, userDB.Create(user))
+	}
+
+	users, err := userDB.FindAll(1, 5, "asc")
+	assert.Nil(t, err)
+	assert.Len(t, users, 5)
+	assert.Equal(t, "User 01", users[0].Name)
+
+	users, err = userDB.FindAll(3, 5, "asc")
+	assert.Nil(t, err)
+	assert.Len(t, users, 2)
+	assert.Equal(t, "User 12", users[1].Name)
+
+	users, err = userDB.FindAll(0, 0, "desc")
+	assert.Nil(t, err)
+	assert.Len(t, users, 12)
+	assert.Equal(t, "User 12", users[0].Name)
+}
+
+func TestUpdateAndDeleteUser(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.User{})
+	user, _ := entity.NewUser("user test", "user@teste.com", "123456")
+	userDB := NewUser(db)
+	assert.Nil(t, userDB.Create(user))
+
+	user.Name = "user updated"
+	assert.Nil(t, userDB.Update(user))
+	userByID, err := userDB.FindByID(user.ID.String())
+	assert.Nil(t, err)
+	assert.Equal(t, "user updated", userByID.Name)
+
+	assert.Nil(t, userDB.Delete(user.ID.String()))
+	_, err = userDB.FindByID(user.ID.String())
+	assert.Error(t, err)
+
+	// Alterar ou remover um usuário que não existe é erro
+	assert.Error(t, userDB.Update(user))
+	assert.Error(t, userDB.Delete(user.ID.String()))
+}
+
+func TestCreateUserWithRegisteredEmail(t *testing.T) {
+	// Com o TranslateError, igual ao database.Open, o erro do índice único vira gorm.ErrDuplicatedKey
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{TranslateError: true})
+	if err != nil {
+		t.Error(err)
+	}
+	db.AutoMigrate(&entity.User{})
+	userDB := NewUser(db)
+	user, _ := entity.NewUser("user test", "user@teste.com", "123456")
+	assert.Nil(t, userDB.Create(user))
+
+	// O email é normalizado no NewUser, então outra grafia do mesmo email também é recusada
+	other, _ := entity.NewUser("other", " USER@Teste.com ", "123456")
+	assert.Equal(t, entity.ErrEmailAlreadyRegistered, userDB.Create(other))
+
+	// A busca usa a mesma normalização
+	found, err := userDB.FindByEmail("User@TESTE.com")
+	assert.Nil(t, err)
+	assert.Equal(t, user.ID, found.ID)
+	_, err = userDB.FindByEmail("not an email")
+	assert.Equal(t, gorm.ErrRecordNotFound, err)
+}
+
+func TestFindByEmailUsesUniqueIndex(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.AutoMigrate(&entity.User{})
+	// Mesmo índice da migração 0007 no sqlite e no postgres
+	assert.Nil(t, db.Exec("DROP INDEX idx_users_email").Error)
+	assert.Nil(t, db.Exec("CREATE UNIQUE INDEX idx_users_email ON users (LOWER(email))").Error)
+	userDB := NewUser(db)
+	user, _ := entity.NewUser("user test", "user@teste.com", "123456")
+	assert.Nil(t, userDB.Create(user))
+
+	found, err := userDB.FindByEmail("User@Teste.com")
+	assert.Nil(t, err)
+	assert.Equal(t, user.ID, found.ID)
+
+	var plan []struct{ Detail string }
+	err = db.Raw("EXPLAIN QUERY PLAN SELECT * FROM users WHERE "+emailCondition(db), user.Email).Scan(&plan).Error
+	assert.Nil(t, err)
+	assert.NotEmpty(t, plan)
+	for _, step := range plan {
+		assert.Contains(t, step.Detail, "idx_users_email")
+	}
+}
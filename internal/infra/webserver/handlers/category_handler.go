@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/waanvieira/api-users/internal/dto"
+	"github.com/waanvieira/api-users/internal/entity"
+	"github.com/waanvieira/api-users/internal/infra/database"
+	"github.com/waanvieira/api-users/internal/infra/webserver/problem"
+	entityPkg "github.com/waanvieira/api-users/pkg/entity"
+)
+
+type CategoryHandler struct {
+	CategoryDB database.CategoryInterface
+}
+
+func NewCategoryHandler(db database.CategoryInterface) *CategoryHandler {
+	return &CategoryHandler{
+		CategoryDB: db,
+	}
+}
+
+// CreateCategory godoc
+// @Summary      Create category
+// @Description  Create a category, at the root or under parent_id
+// @Tags         categories
+// @Accept       json
+// @Produce      json
+// @Param        request     body      dto.CategoryInput  true  "category request"
+// @Success      201         {object}  entity.Category
+// @Failure      400         {object}  problem.Problem
+// @Failure      401         {object}  problem.Problem
+// @Failure      403         {object}  problem.Problem
+// @Failure      422         {object}  problem.Problem
+// @Failure      500         {object}  problem.Problem
+// @Router       /categories [post]
+// @Security ApiKeyAuth
+func (h *CategoryHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	var input dto.CategoryInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		problem.Write(w, r, invalidBody(err))
+		return
+	}
+	parentID, err := parseParentID(input.ParentID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	category, err := entity.NewCategory(input.Name, parentID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	if err := h.CategoryDB.Create(category); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(category)
+}
+
+// GetCategories godoc
+// @Summary      List categories
+// @Description  List every category as a tree, each root with its children
+// @Tags         categories
+// @Produce      json
+// @Success      200         {array}   entity.Category
+// @Failure      401         {object}  problem.Problem
+// @Failure      500         {object}  problem.Problem
+// @Router       /categories [get]
+// @Security ApiKeyAuth
+func (h *CategoryHandler) GetCategories(w http.ResponseWriter, r *http.Request) {
+	categories, err := h.CategoryDB.FindAll()
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	tree := entity.BuildCategoryTree(categories)
+	if tree == nil {
+		tree = []entity.Category{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tree)
+}
+
+// GetCategory godoc
+// @Summary      Get a category
+// @Description  Get a category
+// @Tags         categories
+// @Produce      json
+// @Param        id   path      string  true  "category ID" Format(uuid)
+// @Success      200  {object}  entity.Category
+// @Failure      401  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /categories/{id} [get]
+// @Security ApiKeyAuth
+func (h *CategoryHandler) GetCategory(w http.ResponseWriter, r *http.Request) {
+	category, err := h.CategoryDB.FindByID(chi.URLParam(r, "id"))
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(category)
+}
+
+// UpdateCategory godoc
+// @Summary      Update a category
+// @Description  Rename a category or move it to another parent, moving it under one of its own subcategories is refused
+// @Tags         categories
+// @Accept       json
+// @Produce      json
+// @Param        id          path      string             true  "category ID" Format(uuid)
+// @Param        request     body      dto.CategoryInput  true  "category request"
+// @Success      200         {object}  entity.Category
+// @Failure      400         {object}  problem.Problem
+// @Failure      401         {object}  problem.Problem
+// @Failure      403         {object}  problem.Problem
+// @Failure      404         {object}  problem.Problem
+// @Failure      422         {object}  problem.Problem
+// @Failure      500         {object}  problem.Problem
+// @Router       /categories/{id} [put]
+// @Security ApiKeyAuth
+func (h *CategoryHandler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	category, err := h.CategoryDB.FindByID(chi.URLParam(r, "id"))
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	var input dto.CategoryInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		problem.Write(w, r, invalidBody(err))
+		return
+	}
+	category.ParentID, err = parseParentID(input.ParentID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	category.Name = input.Name
+	if err := category.Validate(); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	if err := h.CategoryDB.Update(category); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(category)
+}
+
+// DeleteCategory godoc
+// @Summary      Delete a category
+// @Description  Delete a category without subcategories, its products stay in the catalog
+// @Tags         categories
+// @Param        id          path      string  true  "category ID" Format(uuid)
+// @Success      204
+// @Failure      401         {object}  problem.Problem
+// @Failure      403         {object}  problem.Problem
+// @Failure      404         {object}  problem.Problem
+// @Failure      409         {object}  problem.Problem
+// @Failure      500         {object}  problem.Problem
+// @Router       /categories/{id} [delete]
+// @Security ApiKeyAuth
+func (h *CategoryHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	if err := h.CategoryDB.Delete(chi.URLParam(r, "id")); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseParentID converte o parent_id do body, vazio ou null deixa a categoria na raiz
+func parseParentID(parentID *string) (*entityPkg.ID, error) {
+	if parentID == nil || *parentID == "" {
+		return nil, nil
+	}
+	id, err := entityPkg.ParseID(*parentID)
+	if err != nil {
+		return nil, entity.ErrCategoryParentNotFound
+	}
+	return &id, nil
+}
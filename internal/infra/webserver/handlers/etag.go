@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/waanvieira/api-users/internal/entity"
+	"github.com/waanvieira/api-users/internal/infra/webserver/problem"
+)
+
+var ErrIfMatchRequired = problem.New(http.StatusPreconditionRequired, "if_match_required", "If-Match header is required, send the ETag returned by GET /products/{id}")
+
+// productETag é o ETag forte do produto, muda a cada alteração porque usa a versão
+func productETag(p *entity.Product) string {
+	return `"` + strconv.FormatInt(p.Version, 10) + `"`
+}
+
+// checkIfMatch compara o If-Match com a versão atual do produto
+// Sem o header a alteração só é aceita quando RequireIfMatch está desligado
+func (h *ProductHandler) checkIfMatch(r *http.Request, current *entity.Product) error {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		if h.Config.RequireIfMatch {
+			return ErrIfMatchRequired
+		}
+		return nil
+	}
+	etag := productETag(current)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == etag {
+			return nil
+		}
+	}
+	return entity.ErrVersionConflict
+}
+
+// cacheValidators são os headers que permitem ao cliente revalidar um GET sem baixar de novo
+type cacheValidators struct {
+	ETag         string
+	LastModified time.Time
+	// Força "private" no Cache-Control quando a resposta depende do usuário
+	Private bool
+}
+
+// setCacheHeaders envia ETag, Last-Modified e Cache-Control, inclusive no 304
+func (h *ProductHandler) setCacheHeaders(w http.ResponseWriter, v cacheValidators) {
+	w.Header().Set("ETag", v.ETag)
+	if !v.LastModified.IsZero() {
+		w.Header().Set("Last-Modified", v.LastModified.UTC().Format(http.TimeFormat))
+	}
+	cacheControl := h.Config.CacheControl
+	if v.Private {
+		cacheControl = "private, no-cache"
+	}
+	if cacheControl != "" {
+		w.Header().Set("Cache-Control", cacheControl)
+	}
+}
+
+// notModified avalia o If-None-Match e, só na ausência dele, o If-Modified-Since (RFC 9110)
+// Com lastModified zero o If-Modified-Since é ignorado
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		// Comparação fraca: W/"x" e "x" representam o mesmo conteúdo
+		etag = strings.TrimPrefix(etag, "W/")
+		for _, tag := range strings.Split(header, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == etag {
+				return true
+			}
+		}
+		return false
+	}
+	if lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	// O header só tem precisão de segundos
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// bodyETag é o ETag de uma resposta que não tem versão, calculado a partir do corpo
+func bodyETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// lastModified é a alteração mais recente entre os produtos
+func lastModified(products []entity.Product) time.Time {
+	var last time.Time
+	for _, p := range products {
+		if p.UpdatedAt.After(last) {
+			last = p.UpdatedAt
+		}
+	}
+	return last
+}
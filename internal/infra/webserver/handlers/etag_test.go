@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/waanvieira/api-users/internal/entity"
+)
+
+func TestFindByIDConditionalGet(t *testing.T) {
+	productDB, router := newTestProductHandler(t, ProductHandlerConfig{CacheControl: "public, max-age=60"})
+	product := createTestProduct(t, productDB, "cadeira")
+	target := "/products/" + product.ID.String()
+	get := func(headers map[string]string) *http.Response {
+		r := newRequest(http.MethodGet, target, "", testOwnerID.String(), entity.RoleViewer)
+		for name, value := range headers {
+			r.Header.Set(name, value)
+		}
+		return serve(router, r).Result()
+	}
+
+	res := get(nil)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, `"1"`, res.Header.Get("ETag"))
+	assert.Equal(t, "public, max-age=60", res.Header.Get("Cache-Control"))
+	lastModified := res.Header.Get("Last-Modified")
+	modifiedAt, err := http.ParseTime(lastModified)
+	assert.NoError(t, err)
+	// O header tem precisão de segundos, o updated_at guardado não
+	assert.Equal(t, product.UpdatedAt.UTC().Truncate(time.Second), modifiedAt)
+
+	// ETag igual, fraco ou * respondem 304 com os mesmos validadores e sem corpo
+	for _, ifNoneMatch := range []string{`"1"`, `W/"1"`, `"9", "1"`, `*`} {
+		res = get(map[string]string{"If-None-Match": ifNoneMatch})
+		assert.Equal(t, http.StatusNotModified, res.StatusCode, ifNoneMatch)
+		assert.Equal(t, `"1"`, res.Header.Get("ETag"))
+		assert.Equal(t, lastModified, res.Header.Get("Last-Modified"))
+		assert.Equal(t, "public, max-age=60", res.Header.Get("Cache-Control"))
+		body, _ := io.ReadAll(res.Body)
+		assert.Empty(t, body)
+	}
+	assert.Equal(t, http.StatusOK, get(map[string]string{"If-None-Match": `"2"`}).StatusCode)
+
+	// If-Modified-Since igual ao Last-Modified é 304 mesmo com as frações de segundo do updated_at
+	assert.Equal(t, http.StatusNotModified, get(map[string]string{"If-Modified-Since": lastModified}).StatusCode)
+	before := modifiedAt.Add(-time.Second).Format(http.TimeFormat)
+	assert.Equal(t, http.StatusOK, get(map[string]string{"If-Modified-Since": before}).StatusCode)
+	assert.Equal(t, http.StatusOK, get(map[string]string{"If-Modified-Since": "ontem"}).StatusCode)
+
+	// Com os dois headers vale apenas o If-None-Match
+	res = get(map[string]string{"If-None-Match": `"2"`, "If-Modified-Since": lastModified})
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	res = get(map[string]string{"If-None-Match": `"1"`, "If-Modified-Since": before})
+	assert.Equal(t, http.StatusNotModified, res.StatusCode)
+}
+
+func TestGetAllProductsConditionalGet(t *testing.T) {
+	productDB, router := newTestProductHandler(t, ProductHandlerConfig{CacheControl: "public, max-age=60"})
+	createTestProduct(t, productDB, "cadeira")
+	createTestProduct(t, productDB, "mesa")
+	get := func(target string, headers map[string]string) *http.Response {
+		r := newRequest(http.MethodGet, target, "", testOwnerID.String(), entity.RoleViewer)
+		for name, value := range headers {
+			r.Header.Set(name, value)
+		}
+		return serve(router, r).Result()
+	}
+
+	res := get("/products", nil)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	etag := res.Header.Get("ETag")
+	assert.NotEmpty(t, etag)
+	assert.NotEmpty(t, res.Header.Get("Last-Modified"))
+	assert.Equal(t, "public, max-age=60", res.Header.Get("Cache-Control"))
+
+	res = get("/products", map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusNotModified, res.StatusCode)
+	assert.Equal(t, etag, res.Header.Get("ETag"))
+	assert.Equal(t, "2", res.Header.Get("X-Total-Count"))
+
+	// A lista só é validada pelo ETag, remover um produto não muda o Last-Modified da página
+	lastModified := res.Header.Get("Last-Modified")
+	assert.Equal(t, http.StatusOK, get("/products", map[string]string{"If-Modified-Since": lastModified}).StatusCode)
+
+	// Outra página tem outro ETag
+	res = get("/products?limit=1", map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.NotEqual(t, etag, res.Header.Get("ETag"))
+
+	// A lista do usuário não pode ficar em cache compartilhado
+	res = get("/products?mine=true", nil)
+	assert.Equal(t, "private, no-cache", res.Header.Get("Cache-Control"))
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/waanvieira/api-users/internal/dto"
+	"github.com/waanvieira/api-users/internal/entity"
+	"github.com/waanvieira/api-users/internal/infra/database"
+	"github.com/waanvieira/api-users/internal/infra/webserver/problem"
+)
+
+// Tamanho da página quando nem o ?limit= nem o DefaultLimit da configuração são informados
+const defaultPageLimit = 20
+
+// cursorPosition é o conteúdo do cursor: a posição na ordenação e a ordenação para a qual ela vale
+type cursorPosition struct {
+	Sort string `json:"s"`
+	database.Keyset
+}
+
+// pagination guarda os links e os metadados da página que vão nos headers e no envelope da resposta
+type pagination struct {
+	links      []string
+	nextCursor string
+	prevCursor string
+	// 0 nas páginas por cursor depois da primeira, o número delas não é conhecido
+	page  int
+	limit int
+	total int64
+}
+
+// pageLimit lê o ?limit= com os limites da configuração do handler de produtos
+func (h *ProductHandler) pageLimit(r *http.Request) (int, error) {
+	return parseLimit(r, h.Config.DefaultLimit, h.Config.MaxLimit)
+}
+
+// parseLimit lê o ?limit=: vazio ou 0 usa o padrão e acima do máximo (0 não limita) é reduzido ao máximo,
+// assim nenhuma requisição devolve a tabela inteira
+func parseLimit(r *http.Request, defaultLimit, maxLimit int) (int, error) {
+	limit := defaultLimit
+	if limit <= 0 {
+		limit = defaultPageLimit
+	}
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return 0, problem.New(http.StatusBadRequest, "invalid_query", "limit must be a positive number")
+		}
+		if n > 0 {
+			limit = n
+		}
+	}
+	if maxLimit > 0 && limit > maxLimit {
+		limit = maxLimit
+	}
+	return limit, nil
+}
+
+// findPage busca a página por cursor (?after= ou ?before=), os links seguem com o mesmo limit, sort e filtros
+func (h *ProductHandler) findPage(r *http.Request, sort []database.SortField, limit int, filter database.ProductFilter) ([]entity.Product, pagination, error) {
+	result := pagination{limit: limit}
+	query := r.URL.Query()
+	if query.Get("after") != "" && query.Get("before") != "" {
+		return nil, result, problem.New(http.StatusBadRequest, "invalid_query", "use after or before, not both")
+	}
+	page := database.CursorPage{Limit: limit, WithTotal: true}
+	var err error
+	if page.After, err = h.decodeCursor(query.Get("after"), sort); err != nil {
+		return nil, result, err
+	}
+	if page.Before, err = h.decodeCursor(query.Get("before"), sort); err != nil {
+		return nil, result, err
+	}
+
+	products, info, err := h.ProductDB.FindPage(sort, page, filter)
+	if err != nil {
+		return nil, result, err
+	}
+	result.total = info.Total
+	if page.After == nil && page.Before == nil {
+		result.page = 1
+	}
+	limitParam := strconv.Itoa(page.Limit)
+	if info.HasNext && info.Last != nil {
+		if result.nextCursor, err = h.encodeCursor(info.Last, sort); err != nil {
+			return nil, result, err
+		}
+		result.links = append(result.links, pageLink(r, "next", map[string]string{"after": result.nextCursor, "limit": limitParam}))
+	}
+	if info.HasPrev && info.First != nil {
+		if result.prevCursor, err = h.encodeCursor(info.First, sort); err != nil {
+			return nil, result, err
+		}
+		result.links = append(result.links, pageLink(r, "prev", map[string]string{"before": result.prevCursor, "limit": limitParam}))
+	}
+	return products, result, nil
+}
+
+// findOffset é a paginação antiga por ?page= e ?limit=, mantida para os clientes que já usam
+func (h *ProductHandler) findOffset(r *http.Request, page, limit int, sort []database.SortField, filter database.ProductFilter) ([]entity.Product, pagination, error) {
+	result := pagination{page: page, limit: limit}
+	products, err := h.ProductDB.FindAll(page, limit, sort, filter)
+	if err != nil {
+		return nil, result, err
+	}
+	if result.total, err = h.ProductDB.Count(filter); err != nil {
+		return nil, result, err
+	}
+	result.offsetLinks(r)
+	return products, result, nil
+}
+
+// offsetLinks monta os links da página anterior e da seguinte pelo ?page=, usando o total para saber se existe a seguinte
+func (p *pagination) offsetLinks(r *http.Request) {
+	limitParam := strconv.Itoa(p.limit)
+	if int64(p.page*p.limit) < p.total {
+		p.links = append(p.links, pageLink(r, "next", map[string]string{"page": strconv.Itoa(p.page + 1), "limit": limitParam}))
+	}
+	if p.page > 1 {
+		p.links = append(p.links, pageLink(r, "prev", map[string]string{"page": strconv.Itoa(p.page - 1), "limit": limitParam}))
+	}
+}
+
+// meta devolve os metadados do envelope, total_pages é o total dividido pelo limit arredondado para cima
+func (p pagination) meta() dto.PageMeta {
+	meta := dto.PageMeta{
+		Page:       p.page,
+		Limit:      p.limit,
+		Total:      p.total,
+		NextCursor: p.nextCursor,
+		PrevCursor: p.prevCursor,
+	}
+	if p.limit > 0 {
+		meta.TotalPages = (p.total + int64(p.limit) - 1) / int64(p.limit)
+	}
+	return meta
+}
+
+// writeHeaders envia o Link (RFC 8288), os cursores e o X-Total-Count
+func (p pagination) writeHeaders(w http.ResponseWriter) {
+	if len(p.links) > 0 {
+		w.Header().Set("Link", strings.Join(p.links, ", "))
+	}
+	if p.nextCursor != "" {
+		w.Header().Set("X-Next-Cursor", p.nextCursor)
+	}
+	if p.prevCursor != "" {
+		w.Header().Set("X-Prev-Cursor", p.prevCursor)
+	}
+	w.Header().Set("X-Total-Count", strconv.FormatInt(p.total, 10))
+}
+
+// decodeCursor valida a assinatura do cursor e se ele foi gerado para a mesma ordenação, vazio devolve nil
+func (h *ProductHandler) decodeCursor(token string, sort []database.SortField) (*database.Keyset, error) {
+	if token == "" {
+		return nil, nil
+	}
+	var position cursorPosition
+	if h.Config.Cursors == nil {
+		return nil, database.ErrInvalidCursor
+	}
+	if err := h.Config.Cursors.Decode(token, &position); err != nil {
+		return nil, database.ErrInvalidCursor
+	}
+	if position.Sort != database.SortString(sort) {
+		return nil, fmt.Errorf("%w: the cursor was created for sort=%s", database.ErrInvalidCursor, position.Sort)
+	}
+	return &position.Keyset, nil
+}
+
+func (h *ProductHandler) encodeCursor(position *database.Keyset, sort []database.SortField) (string, error) {
+	if h.Config.Cursors == nil {
+		return "", errors.New("cursor signer is not configured")
+	}
+	return h.Config.Cursors.Encode(cursorPosition{Sort: database.SortString(sort), Keyset: *position})
+}
+
+// pageLink monta um link para outra página com os mesmos parâmetros da requisição, trocando os de paginação
+func pageLink(r *http.Request, rel string, params map[string]string) string {
+	query := r.URL.Query()
+	for _, name := range []string{"page", "after", "before"} {
+		query.Del(name)
+	}
+	for name, value := range params {
+		query.Set(name, value)
+	}
+	return fmt.Sprintf(`<%s?%s>; rel="%s"`, r.URL.Path, query.Encode(), rel)
+}
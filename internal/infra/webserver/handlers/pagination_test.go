@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/waanvieira/api-users/internal/dto"
+	"github.com/waanvieira/api-users/internal/entity"
+	"github.com/waanvieira/api-users/pkg/cursor"
+)
+
+var linkPattern = regexp.MustCompile(`<([^>]+)>; rel="(\w+)"`)
+
+// pageLinks separa o header Link por rel
+func pageLinks(header string) map[string]string {
+	links := map[string]string{}
+	for _, match := range linkPattern.FindAllStringSubmatch(header, -1) {
+		links[match[2]] = match[1]
+	}
+	return links
+}
+
+// getProducts faz o GET e devolve a resposta com os produtos do array puro
+func getProducts(t *testing.T, router http.Handler, target string) (*http.Response, []entity.Product) {
+	t.Helper()
+	rec := serve(router, newRequest(http.MethodGet, target, "", testOwnerID.String(), entity.RoleViewer))
+	res := rec.Result()
+	var products []entity.Product
+	if res.StatusCode == http.StatusOK {
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&products))
+	}
+	return res, products
+}
+
+func names(products []entity.Product) []string {
+	names := make([]string, len(products))
+	for i, p := range products {
+		names[i] = p.Name
+	}
+	return names
+}
+
+func TestGetAllProductsCursorLinks(t *testing.T) {
+	productDB, router := newTestProductHandler(t, ProductHandlerConfig{})
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		createTestProduct(t, productDB, name)
+	}
+
+	// Primeira página: só o next, mantendo o limit e o sort
+	res, products := getProducts(t, router, "/products?sort=name&limit=2")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, []string{"a", "b"}, names(products))
+	links := pageLinks(res.Header.Get("Link"))
+	assert.NotContains(t, links, "prev")
+	next, err := url.Parse(links["next"])
+	assert.NoError(t, err)
+	assert.Equal(t, "/products", next.Path)
+	assert.Equal(t, "name", next.Query().Get("sort"))
+	assert.Equal(t, "2", next.Query().Get("limit"))
+	assert.Equal(t, res.Header.Get("X-Next-Cursor"), next.Query().Get("after"))
+	assert.Empty(t, res.Header.Get("X-Prev-Cursor"))
+	assert.Equal(t, "5", res.Header.Get("X-Total-Count"))
+
+	// Página do meio: next e prev
+	res, products = getProducts(t, router, links["next"])
+	assert.Equal(t, []string{"c", "d"}, names(products))
+	links = pageLinks(res.Header.Get("Link"))
+	assert.Contains(t, links, "prev")
+	assert.Equal(t, res.Header.Get("X-Prev-Cursor"), mustQuery(t, links["prev"]).Get("before"))
+
+	// Um produto cadastrado no meio da navegação não faz a próxima página repetir itens
+	createTestProduct(t, productDB, "aa")
+	res, products = getProducts(t, router, links["next"])
+	assert.Equal(t, []string{"e"}, names(products))
+	assert.NotContains(t, pageLinks(res.Header.Get("Link")), "next")
+	assert.Equal(t, "6", res.Header.Get("X-Total-Count"))
+
+	// Voltando pelo prev
+	_, products = getProducts(t, router, pageLinks(res.Header.Get("Link"))["prev"])
+	assert.Equal(t, []string{"c", "d"}, names(products))
+}
+
+func mustQuery(t *testing.T, link string) url.Values {
+	t.Helper()
+	u, err := url.Parse(link)
+	assert.NoError(t, err)
+	return u.Query()
+}
+
+func TestGetAllProductsRejectsInvalidCursors(t *testing.T) {
+	productDB, router := newTestProductHandler(t, ProductHandlerConfig{})
+	for _, name := range []string{"a", "b", "c"} {
+		createTestProduct(t, productDB, name)
+	}
+	res, _ := getProducts(t, router, "/products?sort=name&limit=1")
+	next := res.Header.Get("X-Next-Cursor")
+	assert.NotEmpty(t, next)
+
+	invalid := func(target, code string) {
+		t.Helper()
+		rec := serve(router, newRequest(http.MethodGet, target, "", testOwnerID.String(), entity.RoleViewer))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, target)
+		assert.Equal(t, code, decodeProblem(t, rec).Code, target)
+	}
+	// Cursor alterado: a assinatura não bate
+	tampered := next[:len(next)-2] + strings.Map(func(r rune) rune {
+		if r == 'A' {
+			return 'B'
+		}
+		return 'A'
+	}, next[len(next)-2:])
+	invalid("/products?sort=name&limit=1&after="+url.QueryEscape(tampered), "invalid_cursor")
+	invalid("/products?sort=name&after=lixo", "invalid_cursor")
+	// Cursor de outra ordenação
+	invalid("/products?sort=-price&after="+url.QueryEscape(next), "invalid_cursor")
+	invalid("/products?sort=name&after="+url.QueryEscape(next)+"&before="+url.QueryEscape(next), "invalid_query")
+
+	// Cursor assinado com outra chave
+	_, other := newTestProductHandler(t, ProductHandlerConfig{Cursors: cursor.NewSigner([]byte("other-secret"))})
+	rec := serve(other, newRequest(http.MethodGet, "/products?sort=name&after="+url.QueryEscape(next), "", testOwnerID.String(), entity.RoleViewer))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "invalid_cursor", decodeProblem(t, rec).Code)
+}
+
+func TestGetAllProductsLimits(t *testing.T) {
+	productDB, router := newTestProductHandler(t, ProductHandlerConfig{DefaultLimit: 2, MaxLimit: 3})
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		createTestProduct(t, productDB, name)
+	}
+
+	// Sem limit ou com 0 usa o padrão, acima do máximo é reduzido, nos dois tipos de paginação
+	for target, expected := range map[string]int{
+		"/products":                 2,
+		"/products?limit=0":         2,
+		"/products?limit=1":         1,
+		"/products?limit=50":        3,
+		"/products?page=1&limit=50": 3,
+		"/products?page=2":          2,
+	} {
+		res, products := getProducts(t, router, target)
+		assert.Equal(t, http.StatusOK, res.StatusCode, target)
+		assert.Len(t, products, expected, target)
+		assert.Equal(t, "5", res.Header.Get("X-Total-Count"), target)
+	}
+	// O link da próxima página leva o limit já reduzido
+	res, _ := getProducts(t, router, "/products?sort=name&limit=50")
+	assert.Equal(t, "3", mustQuery(t, pageLinks(res.Header.Get("Link"))["next"]).Get("limit"))
+
+	for _, limit := range []string{"abc", "-1", "1.5"} {
+		rec := serve(router, newRequest(http.MethodGet, "/products?limit="+limit, "", testOwnerID.String(), entity.RoleViewer))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, limit)
+		assert.Equal(t, "invalid_query", decodeProblem(t, rec).Code, limit)
+	}
+}
+
+func TestGetAllProductsOffsetLinks(t *testing.T) {
+	productDB, router := newTestProductHandler(t, ProductHandlerConfig{})
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		createTestProduct(t, productDB, name)
+	}
+
+	res, products := getProducts(t, router, "/products?page=2&limit=2&sort=name")
+	assert.Equal(t, []string{"c", "d"}, names(products))
+	links := pageLinks(res.Header.Get("Link"))
+	assert.Equal(t, "3", mustQuery(t, links["next"]).Get("page"))
+	assert.Equal(t, "1", mustQuery(t, links["prev"]).Get("page"))
+	assert.Equal(t, "name", mustQuery(t, links["next"]).Get("sort"))
+	assert.Equal(t, "5", res.Header.Get("X-Total-Count"))
+
+	res, _ = getProducts(t, router, "/products?page=3&limit=2&sort=name")
+	assert.NotContains(t, pageLinks(res.Header.Get("Link")), "next")
+}
+
+func TestGetAllProductsEnvelope(t *testing.T) {
+	productDB, router := newTestProductHandler(t, ProductHandlerConfig{})
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		createTestProduct(t, productDB, name)
+	}
+	envelope := func(target string) dto.ProductListOutput {
+		t.Helper()
+		rec := serve(router, newRequest(http.MethodGet, target, "", testOwnerID.String(), entity.RoleViewer))
+		assert.Equal(t, http.StatusOK, rec.Code, target)
+		var output dto.ProductListOutput
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&output))
+		assert.Equal(t, strconv.FormatInt(output.Meta.Total, 10), rec.Header().Get("X-Total-Count"))
+		return output
+	}
+
+	output := envelope("/products?envelope=true&page=2&limit=2&sort=name")
+	assert.Equal(t, []string{"c", "d"}, names(output.Data))
+	assert.Equal(t, dto.PageMeta{Page: 2, Limit: 2, Total: 5, TotalPages: 3}, output.Meta)
+
+	// Por cursor o meta traz os cursores e o page só na primeira página
+	output = envelope("/products?envelope=true&limit=2&sort=name")
+	assert.Equal(t, 1, output.Meta.Page)
+	assert.NotEmpty(t, output.Meta.NextCursor)
+	assert.Empty(t, output.Meta.PrevCursor)
+	output = envelope("/products?envelope=true&limit=2&sort=name&after=" + url.QueryEscape(output.Meta.NextCursor))
+	assert.Equal(t, []string{"c", "d"}, names(output.Data))
+	assert.Zero(t, output.Meta.Page)
+	assert.NotEmpty(t, output.Meta.PrevCursor)
+	assert.Equal(t, int64(3), output.Meta.TotalPages)
+
+	// Sem produtos o data é um array vazio, não null
+	rec := serve(router, newRequest(http.MethodGet, "/products?envelope=true&tags=nenhuma", "", testOwnerID.String(), entity.RoleViewer))
+	assert.Contains(t, rec.Body.String(), `"data":[]`)
+	assert.Equal(t, "0", rec.Header().Get("X-Total-Count"))
+}
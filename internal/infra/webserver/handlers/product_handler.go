@@ -1,26 +1,55 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"io"
+	"mime"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi"
+	"github.com/go-chi/jwtauth"
 	"github.com/waanvieira/api-users/internal/dto"
 	"github.com/waanvieira/api-users/internal/entity"
 	"github.com/waanvieira/api-users/internal/infra/database"
+	"github.com/waanvieira/api-users/internal/infra/webserver/problem"
+	"github.com/waanvieira/api-users/pkg/cursor"
 	entityPkg "github.com/waanvieira/api-users/pkg/entity"
+	queryfilter "github.com/waanvieira/api-users/pkg/filter"
+	"github.com/waanvieira/api-users/pkg/mergepatch"
 )
 
+// ProductHandlerConfig são as opções dos endpoints de produto que vêm da configuração
+type ProductHandlerConfig struct {
+	// Exige o header If-Match no PUT, PATCH e DELETE, sem ele a api responde 428
+	RequireIfMatch bool
+	// Cache-Control das respostas de GET, vazio não envia o header
+	CacheControl string
+	// Assina os cursores da paginação (?after= e ?before=)
+	Cursors *cursor.Signer
+	// Tamanho da página sem ?limit=, 0 usa 20
+	DefaultLimit int
+	// Maior ?limit= aceito, 0 não limita
+	MaxLimit int
+}
+
 type ProductHandler struct {
-	ProductDB database.ProductInterface
+	ProductDB  database.ProductInterface
+	CategoryDB database.CategoryInterface
+	Config     ProductHandlerConfig
 }
 
 // Aqui é basicamente o nosso construtor, indicando que estamos recebendo a interface, e não a classe concreta
 // Isso é inversão de dependencia
-func NewProductHandler(db database.ProductInterface) *ProductHandler {
+func NewProductHandler(db database.ProductInterface, categoryDB database.CategoryInterface, config ProductHandlerConfig) *ProductHandler {
 	return &ProductHandler{
-		ProductDB: db,
+		ProductDB:  db,
+		CategoryDB: categoryDB,
+		Config:     config,
 	}
 }
 
@@ -32,7 +61,11 @@ func NewProductHandler(db database.ProductInterface) *ProductHandler {
 // @Produce      json
 // @Param        request     body      dto.CreateProductInput  true  "product request"
 // @Success      201
-// @Failure      500         {object}  Error
+// @Failure      400         {object}  problem.Problem
+// @Failure      401         {object}  problem.Problem
+// @Failure      403         {object}  problem.Problem
+// @Failure      422         {object}  problem.Problem
+// @Failure      500         {object}  problem.Problem
 // @Router       /products [post]
 // @Security ApiKeyAuth
 // Função que seria o nosso controller, recebe um request e retorna um response
@@ -46,18 +79,42 @@ func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 	// Hidratando a nossa variável product
 	erro := json.NewDecoder(r.Body).Decode(&product)
 	if erro != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Erro DTO"))
+		problem.Write(w, r, productBodyError(erro))
 		return
 	}
 
-	// Aqui criamos a nossa entidade com os 2 parametros que precisamos
-	p, err := entity.NewProduct(product.Name, product.Price)
+	// O dono do produto é o usuário do token (claim "sub")
+	userID, _ := currentUser(r)
+	ownerID, err := entityPkg.ParseID(userID)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Erro para criar"))
+		problem.Write(w, r, jwtauth.ErrUnauthorized)
 		return
 	}
+
+	// Aqui criamos a nossa entidade com os 2 parametros que precisamos, o restante é opcional
+	options := []entity.ProductOption{
+		entity.WithDescription(product.Description),
+		entity.WithSKU(product.SKU),
+		entity.WithStock(product.Stock),
+		entity.WithTags(product.Tags...),
+	}
+	if product.Status != "" {
+		options = append(options, entity.WithStatus(entity.ProductStatus(product.Status)))
+	}
+	if len(product.CategoryIDs) > 0 {
+		categories, err := h.CategoryDB.FindByIDs(product.CategoryIDs)
+		if err != nil {
+			problem.Write(w, r, err)
+			return
+		}
+		options = append(options, entity.WithCategories(categories...))
+	}
+	p, err := entity.NewProduct(product.Name, product.Price, options...)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	p.OwnerID = &ownerID
 	//  Aqui fazemos o cadastro no banco de dados, com a nossa injeção de dependencia do productDB
 	// no nosso handler da struct
 	// Seria bsicamente fazer igual nos testes
@@ -68,11 +125,9 @@ func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 
 	err = h.ProductDB.Create(p)
 	// atribuimos o create a erro porque é uma função void, não tem retorno, então validamos
-	// se a variável for diferente de nil retornamos um bad request
+	// se a variável for diferente de nil respondemos o erro
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Erro para salvar no banco"))
-
+		problem.Write(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusCreated)
@@ -86,24 +141,36 @@ func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 // @Produce      json
 // @Param        id   path      string  true  "product ID" Format(uuid)
 // @Success      200  {object}  entity.Product
-// @Failure      404
-// @Failure      500  {object}  Error
+// @Param        If-None-Match      header  string  false  "ETag already cached by the client"
+// @Param        If-Modified-Since  header  string  false  "Last-Modified already cached by the client"
+// @Success      304  "product not modified"
+// @Header       200  {string}  ETag  "version of the product, send it in If-Match to change it"
+// @Header       200  {string}  Last-Modified  "date of the last change"
+// @Failure      401  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
 // @Router       /products/{id} [get]
 // @Security ApiKeyAuth
 func (h *ProductHandler) FindByID(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		w.WriteHeader(http.StatusBadRequest)
+		problem.Write(w, r, entity.ErrIDIsRequired)
 		return
 	}
 
 	p, err := h.ProductDB.FindByID(id)
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
-		w.Write([]byte("Registro não encontrado"))
+		problem.Write(w, r, err)
 		return
 	}
 
+	etag := productETag(p)
+	h.setCacheHeaders(w, cacheValidators{ETag: etag, LastModified: p.UpdatedAt})
+	if notModified(r, etag, p.UpdatedAt) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(p)
 }
 
@@ -113,72 +180,211 @@ func (h *ProductHandler) FindByID(w http.ResponseWriter, r *http.Request) {
 // @Tags         products
 // @Accept       json
 // @Produce      json
-// @Param        page      query     string  false  "page number"
-// @Param        limit     query     string  false  "limit"
-// @Success      200       {array}   entity.Product
-// @Failure      404       {object}  Error
-// @Failure      500       {object}  Error
+// @Param        page      query     string  false  "page number, offset pagination kept for older clients"
+// @Param        limit     query     int     false  "page size, without it (or 0) the server default is used and values above the maximum are reduced to it"
+// @Param        envelope  query     bool    false  "wrap the products in {data, meta} with page, limit, total and total_pages"
+// @Param        after     query     string  false  "cursor from X-Next-Cursor, returns the products after it"
+// @Param        before    query     string  false  "cursor from X-Prev-Cursor, returns the products before it"
+// @Param        sort      query     string  false  "comma separated fields, - for descending, e.g. -price,name; fields: name, price, currency, stock, status, sku, created_at, updated_at; asc and desc sort by created_at" default(created_at)
+// @Param        mine      query     bool    false  "only products created by the authenticated user"
+// @Param        category  query     string  false  "only products in the category" Format(uuid)
+// @Param        include_subcategories  query  bool  false  "with category, also products in its subcategories"
+// @Param        tags      query     string  false  "comma separated tags, e.g. promoção,verão"
+// @Param        match     query     string  false  "any (default) returns products with at least one of the tags, all only products with every tag" Enums(any, all)
+// @Param        filter    query     string  false  "filter expression, e.g. price gt 10 and name contains 'chair' and created_at ge 2026-01-01"
+// @Param        If-None-Match  header  string  false  "ETag of the page already cached by the client"
+// @Success      200       {array}   entity.Product  "bare array, or dto.ProductListOutput with envelope=true"
+// @Success      304       "page not modified"
+// @Header       200       {string}  ETag  "hash of the page"
+// @Header       200       {string}  Last-Modified  "most recent change among the products of the page"
+// @Header       200       {string}  Link  "RFC 8288 links to the next and previous pages"
+// @Header       200       {string}  X-Next-Cursor  "cursor of the next page"
+// @Header       200       {string}  X-Prev-Cursor  "cursor of the previous page"
+// @Header       200       {integer} X-Total-Count  "products matching the filters"
+// @Failure      400       {object}  problem.Problem  "invalid filter, sort, match, limit or cursor"
+// @Failure      401       {object}  problem.Problem
+// @Failure      500       {object}  problem.Problem
 // @Router       /products [get]
 // @Security ApiKeyAuth
 func (h *ProductHandler) GetAllProducts(w http.ResponseWriter, r *http.Request) {
-	page := r.URL.Query().Get("page")
-	limit := r.URL.Query().Get("limit")
 	// Automaticamente quando recebemos parametros ele vem como string o pacote strconv é para converter string em number
-	pageInt, err := strconv.Atoi(page)
+	pageInt, err := strconv.Atoi(r.URL.Query().Get("page"))
 	if err != nil {
 		pageInt = 0
 	}
-	limitInt, err := strconv.Atoi(limit)
+	limitInt, err := h.pageLimit(r)
 	if err != nil {
-		limitInt = 0
+		problem.Write(w, r, err)
+		return
 	}
 
-	sort := r.URL.Query().Get("sort")
-	products, err := h.ProductDB.FindAll(pageInt, limitInt, sort)
+	sort, err := database.ParseSort(r.URL.Query().Get("sort"))
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	var filter database.ProductFilter
+	if mine, _ := strconv.ParseBool(r.URL.Query().Get("mine")); mine {
+		filter.OwnerID, _ = currentUser(r)
+	}
+	filter.CategoryID = r.URL.Query().Get("category")
+	filter.IncludeSubcategories, _ = strconv.ParseBool(r.URL.Query().Get("include_subcategories"))
+	filter.Tags, filter.MatchAllTags, err = parseTagFilter(r.URL.Query().Get("tags"), r.URL.Query().Get("match"))
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	// A sintaxe é validada aqui, os campos aceitos são verificados pelo repositório; os dois erros viram 400
+	if expression := r.URL.Query().Get("filter"); expression != "" {
+		filter.Expression, err = queryfilter.Parse(expression)
+		if err != nil {
+			problem.Write(w, r, err)
+			return
+		}
+	}
+	// Sem page a paginação é por cursor, o page continua com offset
+	var products []entity.Product
+	var pages pagination
+	if r.URL.Query().Get("after") != "" || r.URL.Query().Get("before") != "" || pageInt <= 0 {
+		products, pages, err = h.findPage(r, sort, limitInt, filter)
+	} else {
+		products, pages, err = h.findOffset(r, pageInt, limitInt, sort, filter)
+	}
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	// O array puro continua sendo a resposta padrão, o envelope é opcional
+	var response interface{} = products
+	if envelope, _ := strconv.ParseBool(r.URL.Query().Get("envelope")); envelope {
+		if products == nil {
+			products = []entity.Product{}
+		}
+		response = dto.ProductListOutput{Data: products, Meta: pages.meta()}
+	}
+	body, err := json.Marshal(response)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		problem.Write(w, r, err)
+		return
+	}
+	body = append(body, '\n')
+
+	etag := bodyETag(body)
+	validators := cacheValidators{ETag: etag, LastModified: lastModified(products)}
+	// A lista do ?mine=true depende do usuário, então não pode ficar em cache compartilhado
+	validators.Private = filter.OwnerID != ""
+	h.setCacheHeaders(w, validators)
+	pages.writeHeaders(w)
+	// Só o ETag valida a lista: apagar um produto não muda o Last-Modified da página
+	if notModified(r, etag, time.Time{}) {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(products)
+	w.Write(body)
+}
+
+// SearchProducts godoc
+// @Summary      Search products
+// @Description  Search the product names and descriptions, most relevant first. Every word must be found, "quoted text" is a phrase and a trailing * also matches the words starting with the term. Uses the SQLite FTS5 index when available and LIKE otherwise
+// @Tags         products
+// @Produce      json
+// @Param        q         query     string  true   "search text, e.g. cadeira ergo*, with phrases between double quotes"
+// @Param        page      query     int     false  "page number" default(1)
+// @Param        limit     query     int     false  "page size, same default and maximum as GET /products"
+// @Param        envelope  query     bool    false  "wrap the results in {data, meta} with page, limit, total and total_pages"
+// @Success      200       {array}   entity.ProductSearchResult  "bare array, or dto.ProductSearchOutput with envelope=true"
+// @Header       200       {string}  Link  "RFC 8288 links to the next and previous pages"
+// @Header       200       {integer} X-Total-Count  "products found"
+// @Failure      400       {object}  problem.Problem  "invalid q or limit"
+// @Failure      401       {object}  problem.Problem
+// @Failure      500       {object}  problem.Problem
+// @Router       /products/search [get]
+// @Security ApiKeyAuth
+func (h *ProductHandler) SearchProducts(w http.ResponseWriter, r *http.Request) {
+	terms, err := database.ParseSearch(r.URL.Query().Get("q"))
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page <= 0 {
+		page = 1
+	}
+	limit, err := h.pageLimit(r)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	results, total, err := h.ProductDB.Search(terms, page, limit)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	// A ordem é pela relevância, que não serve de cursor; a busca pagina só pelo ?page=
+	pages := pagination{page: page, limit: limit, total: total}
+	pages.offsetLinks(r)
+	if results == nil {
+		results = []entity.ProductSearchResult{}
+	}
+	var response interface{} = results
+	if envelope, _ := strconv.ParseBool(r.URL.Query().Get("envelope")); envelope {
+		response = dto.ProductSearchOutput{Data: results, Meta: pages.meta()}
+	}
+	pages.writeHeaders(w)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "private, no-cache")
+	json.NewEncoder(w).Encode(response)
 }
 
 // DeleteProduct godoc
 // @Summary      Delete a product
-// @Description  Delete a product
+// @Description  Move a product to the trash, it can be restored with POST /products/{id}/restore
 // @Tags         products
 // @Accept       json
 // @Produce      json
 // @Param        id        path      string                  true  "product ID" Format(uuid)
-// @Success      200
-// @Failure      404
-// @Failure      500       {object}  Error
+// @Param        If-Match  header    string                  false "ETag from GET /products/{id}, required when REQUIRE_IF_MATCH is enabled"
+// @Success      204
+// @Header       204       {string}  ETag  "version of the product in the trash"
+// @Failure      401       {object}  problem.Problem
+// @Failure      403       {object}  problem.Problem
+// @Failure      404       {object}  problem.Problem
+// @Failure      412       {object}  problem.Problem
+// @Failure      428       {object}  problem.Problem
+// @Failure      500       {object}  problem.Problem
 // @Router       /products/{id} [delete]
 // @Security ApiKeyAuth
 func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		w.WriteHeader(http.StatusBadRequest)
+		problem.Write(w, r, entity.ErrIDIsRequired)
 		return
 	}
 
-	_, err := h.ProductDB.FindByID(id)
+	// A rota é só para admin, então não verificamos o dono
+	p, err := h.ProductDB.FindByID(id)
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
-		w.Write([]byte("Registro não encontrado"))
+		problem.Write(w, r, err)
+		return
+	}
+	if err := h.checkIfMatch(r, p); err != nil {
+		problem.Write(w, r, err)
 		return
 	}
 
-	err = h.ProductDB.Delete(id)
+	// A versão lida aqui é verificada de novo no UPDATE, assim um PUT ou PATCH concorrente não é perdido
+	err = h.ProductDB.Delete(id, p.Version)
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
-		w.Write([]byte("Registro não encontrado"))
+		problem.Write(w, r, err)
 		return
 	}
 
+	// A remoção muda a versão, o novo ETag serve para o If-Match do restore
+	p.Version++
+	w.Header().Set("ETag", productETag(p))
 	w.WriteHeader(http.StatusNoContent)
-	w.Write([]byte("Registro deletado com sucesso"))
 }
 
 // UpdateProduct godoc
@@ -188,40 +394,372 @@ func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 // @Accept       json
 // @Produce      json
 // @Param        id        	path      string                  true  "product ID" Format(uuid)
+// @Param        If-Match  header    string                  false "ETag from GET /products/{id}, required when REQUIRE_IF_MATCH is enabled"
 // @Param        request     body      dto.CreateProductInput  true  "product request"
 // @Success      200
-// @Failure      404
-// @Failure      500       {object}  Error
+// @Header       200       {string}  ETag  "new version of the product"
+// @Header       200       {string}  Last-Modified  "date of the change"
+// @Failure      400       {object}  problem.Problem
+// @Failure      401       {object}  problem.Problem
+// @Failure      403       {object}  problem.Problem
+// @Failure      404       {object}  problem.Problem
+// @Failure      422       {object}  problem.Problem
+// @Failure      412       {object}  problem.Problem
+// @Failure      428       {object}  problem.Problem
+// @Failure      500       {object}  problem.Problem
 // @Router       /products/{id} [put]
 // @Security ApiKeyAuth
 func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		w.WriteHeader(http.StatusBadRequest)
+		problem.Write(w, r, entity.ErrIDIsRequired)
 		return
 	}
-	var product entity.Product
-	err := json.NewDecoder(r.Body).Decode(&product)
+	productID, err := entityPkg.ParseID(id)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		problem.Write(w, r, entity.ErrInvalidID)
 		return
 	}
-	product.ID, err = entityPkg.ParseID(id)
+	current, err := h.ProductDB.FindByID(id)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		problem.Write(w, r, err)
 		return
 	}
-	_, err = h.ProductDB.FindByID(id)
-	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
+	if !canChangeProduct(r, current) {
+		problem.Write(w, r, entity.ErrNotProductOwner)
+		return
+	}
+	if err := h.checkIfMatch(r, current); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	// Clientes antigos só mandam nome e preço, então os campos do catálogo que não vierem no body ficam como estão
+	product := entity.Product{
+		Description: current.Description,
+		SKU:         current.SKU,
+		Stock:       current.Stock,
+		Status:      current.Status,
+		Tags:        current.Tags,
+	}
+	if err := json.NewDecoder(r.Body).Decode(&product); err != nil {
+		problem.Write(w, r, productBodyError(err))
+		return
+	}
+	product.Tags = entity.UniqueTags(product.Tags)
+	product.ID = productID
+	// O dono, a data de cadastro e a versão não mudam pelo body, a versão só muda ao gravar
+	product.OwnerID = current.OwnerID
+	product.CreatedAt = current.CreatedAt
+	product.Version = current.Version
+	// Mesmas regras do cadastro, todos os campos inválidos voltam juntos
+	if err := product.Validate(); err != nil {
+		problem.Write(w, r, err)
 		return
 	}
 	// Aqui atribuimos a variável como referencia porque o valor já foi setado anteriormente, aqui estamos basicamente atribuindo um novo valor ao err, se mudassemos o valor o nome da variável
 	// teriamos que indicar := que seria atribuição do valor na variável err
 	err = h.ProductDB.Update(&product)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		problem.Write(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", productETag(&product))
+	w.Header().Set("Last-Modified", product.UpdatedAt.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusOK)
+}
+
+// PatchProduct godoc
+// @Summary      Partially update a product
+// @Description  Apply a JSON Merge Patch (RFC 7396) to a product. Only the informed fields change, null removes the value and id, owner_id, version and created_at cannot be changed
+// @Tags         products
+// @Accept       application/merge-patch+json
+// @Produce      json
+// @Param        id        path      string                  true  "product ID" Format(uuid)
+// @Param        If-Match  header    string                  false "ETag from GET /products/{id}, required when REQUIRE_IF_MATCH is enabled"
+// @Param        request   body      dto.CreateProductInput  true  "fields to change"
+// @Success      200       {object}  entity.Product
+// @Header       200       {string}  ETag  "new version of the product"
+// @Header       200       {string}  Last-Modified  "date of the change"
+// @Failure      400       {object}  problem.Problem
+// @Failure      401       {object}  problem.Problem
+// @Failure      403       {object}  problem.Problem
+// @Failure      404       {object}  problem.Problem
+// @Failure      415       {object}  problem.Problem
+// @Failure      422       {object}  problem.Problem
+// @Failure      412       {object}  problem.Problem
+// @Failure      428       {object}  problem.Problem
+// @Failure      500       {object}  problem.Problem
+// @Router       /products/{id} [patch]
+// @Security ApiKeyAuth
+func (h *ProductHandler) PatchProduct(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != mergepatch.ContentType {
+		problem.Write(w, r, problem.New(http.StatusUnsupportedMediaType, "unsupported_media_type", "content type must be "+mergepatch.ContentType))
+		return
+	}
+	id := chi.URLParam(r, "id")
+	current, err := h.ProductDB.FindByID(id)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	if !canChangeProduct(r, current) {
+		problem.Write(w, r, entity.ErrNotProductOwner)
+		return
+	}
+	if err := h.checkIfMatch(r, current); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		problem.Write(w, r, invalidBody(err))
+		return
+	}
+	// O patch é aplicado sobre o JSON do produto atual, assim os campos que não vieram continuam iguais
+	original, err := json.Marshal(current)
+	if err != nil {
+		problem.Write(w, r, err)
 		return
 	}
+	merged, err := mergepatch.Apply(original, patch)
+	if err != nil {
+		problem.Write(w, r, invalidBody(err))
+		return
+	}
+	var product entity.Product
+	decoder := json.NewDecoder(bytes.NewReader(merged))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&product); err != nil {
+		problem.Write(w, r, productBodyError(err))
+		return
+	}
+	// A remoção e as categorias têm fluxo próprio, o patch não altera; a versão é verificada no ValidateChange
+	product.DeletedAt = current.DeletedAt
+	product.Categories = current.Categories
+	product.Tags = entity.UniqueTags(product.Tags)
+	if err := product.ValidateChange(current); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	if err := h.ProductDB.Update(&product); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", productETag(&product))
+	w.Header().Set("Last-Modified", product.UpdatedAt.UTC().Format(http.TimeFormat))
 	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(product)
+}
+
+// GetTrash godoc
+// @Summary      List deleted products
+// @Description  List the products in the trash, editors only see their own products and admins see all of them
+// @Tags         products
+// @Produce      json
+// @Param        page      query     int     false  "page number" default(1)
+// @Param        limit     query     int     false  "page size, same default and maximum as GET /products"
+// @Success      200       {array}   entity.Product
+// @Failure      400       {object}  problem.Problem  "invalid limit"
+// @Failure      401       {object}  problem.Problem
+// @Failure      403       {object}  problem.Problem
+// @Failure      500       {object}  problem.Problem
+// @Router       /products/trash [get]
+// @Security ApiKeyAuth
+func (h *ProductHandler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page <= 0 {
+		page = 1
+	}
+	limit, err := h.pageLimit(r)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	var filter database.ProductFilter
+	userID, role := currentUser(r)
+	if !role.Includes(entity.RoleAdmin) {
+		filter.OwnerID = userID
+	}
+	products, err := h.ProductDB.FindDeleted(page, limit, filter)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "private, no-cache")
+	json.NewEncoder(w).Encode(products)
+}
+
+// RestoreProduct godoc
+// @Summary      Restore a deleted product
+// @Description  Move a product from the trash back to the catalog
+// @Tags         products
+// @Produce      json
+// @Param        id        path      string  true  "product ID" Format(uuid)
+// @Param        If-Match  header    string  false "ETag of the product in the trash (its version, quoted, as listed by GET /products/trash), required when REQUIRE_IF_MATCH is enabled"
+// @Success      200       {object}  entity.Product
+// @Header       200       {string}  ETag  "new version of the product"
+// @Failure      401       {object}  problem.Problem
+// @Failure      403       {object}  problem.Problem
+// @Failure      404       {object}  problem.Problem
+// @Failure      412       {object}  problem.Problem
+// @Failure      428       {object}  problem.Problem
+// @Failure      500       {object}  problem.Problem
+// @Router       /products/{id}/restore [post]
+// @Security ApiKeyAuth
+func (h *ProductHandler) RestoreProduct(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	deleted, err := h.ProductDB.FindDeletedByID(id)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	if !canChangeProduct(r, deleted) {
+		problem.Write(w, r, entity.ErrNotProductOwner)
+		return
+	}
+	// O ETag do produto na lixeira é a versão que aparece no GET /products/trash
+	if err := h.checkIfMatch(r, deleted); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	product, err := h.ProductDB.Restore(id, deleted.Version)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", productETag(product))
+	w.Header().Set("Last-Modified", product.UpdatedAt.UTC().Format(http.TimeFormat))
+	json.NewEncoder(w).Encode(product)
+}
+
+// PurgeProduct godoc
+// @Summary      Permanently delete a product
+// @Description  Remove a product from the trash for good, only admins can do it
+// @Tags         products
+// @Param        id        path      string  true  "product ID" Format(uuid)
+// @Success      204
+// @Failure      401       {object}  problem.Problem
+// @Failure      403       {object}  problem.Problem
+// @Failure      404       {object}  problem.Problem
+// @Failure      500       {object}  problem.Problem
+// @Router       /products/trash/{id} [delete]
+// @Security ApiKeyAuth
+func (h *ProductHandler) PurgeProduct(w http.ResponseWriter, r *http.Request) {
+	if err := h.ProductDB.Purge(chi.URLParam(r, "id")); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// productBodyError devolve os erros do preço no body (moeda ou casas decimais) como validação do campo price
+// O resto continua sendo um body inválido
+func productBodyError(err error) error {
+	for _, target := range []error{entityPkg.ErrInvalidAmount, entityPkg.ErrInvalidScale, entityPkg.ErrUnsupportedCurrency} {
+		if errors.Is(err, target) {
+			var errs entity.ValidationErrors
+			errs.Add("price", entity.CodeInvalid, err)
+			return errs
+		}
+	}
+	return invalidBody(err)
+}
+
+// SetProductCategories godoc
+// @Summary      Set product categories
+// @Description  Replace the categories of a product, an empty list removes all of them
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param        id        path      string                      true  "product ID" Format(uuid)
+// @Param        If-Match  header    string                      false "ETag from GET /products/{id}, required when REQUIRE_IF_MATCH is enabled"
+// @Param        request   body      dto.ProductCategoriesInput  true  "categories of the product"
+// @Success      200       {object}  entity.Product
+// @Header       200       {string}  ETag  "new version of the product"
+// @Failure      400       {object}  problem.Problem
+// @Failure      401       {object}  problem.Problem
+// @Failure      403       {object}  problem.Problem
+// @Failure      404       {object}  problem.Problem
+// @Failure      412       {object}  problem.Problem
+// @Failure      422       {object}  problem.Problem
+// @Failure      428       {object}  problem.Problem
+// @Failure      500       {object}  problem.Problem
+// @Router       /products/{id}/categories [put]
+// @Security ApiKeyAuth
+func (h *ProductHandler) SetProductCategories(w http.ResponseWriter, r *http.Request) {
+	product, err := h.ProductDB.FindByID(chi.URLParam(r, "id"))
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	if !canChangeProduct(r, product) {
+		problem.Write(w, r, entity.ErrNotProductOwner)
+		return
+	}
+	if err := h.checkIfMatch(r, product); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	var input dto.ProductCategoriesInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		problem.Write(w, r, invalidBody(err))
+		return
+	}
+	categories, err := h.CategoryDB.FindByIDs(input.CategoryIDs)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	if err := h.ProductDB.SetCategories(product, categories); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", productETag(product))
+	w.Header().Set("Last-Modified", product.UpdatedAt.UTC().Format(http.TimeFormat))
+	json.NewEncoder(w).Encode(product)
+}
+
+// currentUser pega o id (claim "sub") e o perfil (claim "role") do token validado pelo jwtauth
+func currentUser(r *http.Request) (string, entity.Role) {
+	_, claims, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		return "", ""
+	}
+	sub, _ := claims["sub"].(string)
+	role, _ := claims["role"].(string)
+	return sub, entity.Role(role)
+}
+
+// canChangeProduct libera a alteração apenas para o dono do produto ou para um admin
+func canChangeProduct(r *http.Request, p *entity.Product) bool {
+	userID, role := currentUser(r)
+	return role.Includes(entity.RoleAdmin) || (userID != "" && p.IsOwnedBy(userID))
+}
+
+// parseTagFilter lê o ?tags=a,b&match=all|any da listagem, as tags são normalizadas como no cadastro
+// Sem match vale any, qualquer outro valor é um 400
+func parseTagFilter(tags, match string) ([]string, bool, error) {
+	var matchAll bool
+	switch match {
+	case "", "any":
+	case "all":
+		matchAll = true
+	default:
+		return nil, false, problem.New(http.StatusBadRequest, "invalid_query", "match must be any or all")
+	}
+	var names []string
+	for _, tag := range entity.NewTags(strings.Split(tags, ",")...) {
+		if tag.Name != "" {
+			names = append(names, tag.Name)
+		}
+	}
+	return names, matchAll, nil
 }
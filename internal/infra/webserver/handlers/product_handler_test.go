@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/jwtauth"
+	"github.com/stretchr/testify/assert"
+	"github.com/waanvieira/api-users/internal/entity"
+	databaseCategory "github.com/waanvieira/api-users/internal/infra/database/category"
+	"github.com/waanvieira/api-users/internal/infra/database/migrations"
+	databaseProduct "github.com/waanvieira/api-users/internal/infra/database/product"
+	"github.com/waanvieira/api-users/internal/infra/webserver/problem"
+	"github.com/waanvieira/api-users/pkg/cursor"
+	entityPkg "github.com/waanvieira/api-users/pkg/entity"
+
+	// Banco em memória sqlite
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Dono dos produtos cadastrados nos testes
+var testOwnerID = entityPkg.NewID()
+
+// newTestProductHandler monta o handler de produtos sobre o banco em memória criado pelas migrações
+// As rotas são as mesmas do main, sem os middlewares de autenticação: o usuário vem do contexto da requisição
+func newTestProductHandler(t *testing.T, config ProductHandlerConfig) (*databaseProduct.Product, http.Handler) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatal(err)
+	}
+	if config.Cursors == nil {
+		config.Cursors = cursor.NewSigner([]byte("cursor-secret"))
+	}
+	productDB := databaseProduct.NewProduct(db)
+	h := NewProductHandler(productDB, databaseCategory.NewCategory(db), config)
+
+	r := chi.NewRouter()
+	r.Get("/products", h.GetAllProducts)
+	r.Get("/products/{id}", h.FindByID)
+	r.Put("/products/{id}", h.UpdateProduct)
+	r.Patch("/products/{id}", h.PatchProduct)
+	r.Delete("/products/{id}", h.DeleteProduct)
+	return productDB, r
+}
+
+// createTestProduct cadastra um produto do testOwnerID direto no repositório
+func createTestProduct(t *testing.T, productDB *databaseProduct.Product, name string, options ...entity.ProductOption) *entity.Product {
+	product, err := entity.NewProduct(name, entityPkg.MustParseMoney("10", "BRL"), options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	product.OwnerID = &testOwnerID
+	if err := productDB.Create(product); err != nil {
+		t.Fatal(err)
+	}
+	return product
+}
+
+// newRequest monta a requisição com o token de um usuário já validado no contexto, como faz o auth.Verifier
+func newRequest(method, target, body string, userID string, role entity.Role) *http.Request {
+	ja := jwtauth.New("HS256", []byte("secret"), nil)
+	token, _, _ := ja.Encode(map[string]interface{}{"sub": userID, "role": string(role)})
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	return r.WithContext(jwtauth.NewContext(r.Context(), token, nil))
+}
+
+func serve(handler http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+	return rec
+}
+
+// decodeProblem lê o corpo problem+json da resposta
+func decodeProblem(t *testing.T, rec *httptest.ResponseRecorder) problem.Problem {
+	t.Helper()
+	assert.Equal(t, problem.ContentType, rec.Header().Get("Content-Type"))
+	var p problem.Problem
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&p))
+	return p
+}
+
+func TestUpdateProductIfMatch(t *testing.T) {
+	productDB, router := newTestProductHandler(t, ProductHandlerConfig{RequireIfMatch: true})
+	product := createTestProduct(t, productDB, "cadeira")
+	target := "/products/" + product.ID.String()
+	body := `{"name": "cadeira gamer", "price": {"amount": "15", "currency": "BRL"}}`
+	owner := testOwnerID.String()
+
+	// Sem If-Match
+	rec := serve(router, newRequest(http.MethodPut, target, body, owner, entity.RoleEditor))
+	assert.Equal(t, http.StatusPreconditionRequired, rec.Code)
+	assert.Equal(t, "if_match_required", decodeProblem(t, rec).Code)
+
+	// ETag antigo
+	r := newRequest(http.MethodPut, target, body, owner, entity.RoleEditor)
+	r.Header.Set("If-Match", `"7"`)
+	rec = serve(router, r)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	assert.Equal(t, "version_conflict", decodeProblem(t, rec).Code)
+
+	// ETag atual: altera e devolve o ETag da nova versão
+	r = newRequest(http.MethodPut, target, body, owner, entity.RoleEditor)
+	r.Header.Set("If-Match", `"1"`)
+	rec = serve(router, r)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+	assert.NotEmpty(t, rec.Header().Get("Last-Modified"))
+	updated, err := productDB.FindByID(product.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, "cadeira gamer", updated.Name)
+	assert.Equal(t, int64(2), updated.Version)
+
+	// O ETag usado antes da alteração deixou de valer
+	r = newRequest(http.MethodPut, target, body, owner, entity.RoleEditor)
+	r.Header.Set("If-Match", `"1"`)
+	assert.Equal(t, http.StatusPreconditionFailed, serve(router, r).Code)
+}
+
+func TestPatchAndDeleteProductIfMatch(t *testing.T) {
+	productDB, router := newTestProductHandler(t, ProductHandlerConfig{RequireIfMatch: true})
+	product := createTestProduct(t, productDB, "mesa")
+	target := "/products/" + product.ID.String()
+	owner := testOwnerID.String()
+
+	patch := func(ifMatch string) *httptest.ResponseRecorder {
+		r := newRequest(http.MethodPatch, target, `{"stock": 3}`, owner, entity.RoleEditor)
+		r.Header.Set("Content-Type", "application/merge-patch+json")
+		if ifMatch != "" {
+			r.Header.Set("If-Match", ifMatch)
+		}
+		return serve(router, r)
+	}
+	assert.Equal(t, http.StatusPreconditionRequired, patch("").Code)
+	assert.Equal(t, http.StatusPreconditionFailed, patch(`"2"`).Code)
+	rec := patch(`"0", "1"`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+
+	remove := func(ifMatch string) *httptest.ResponseRecorder {
+		r := newRequest(http.MethodDelete, target, "", owner, entity.RoleAdmin)
+		if ifMatch != "" {
+			r.Header.Set("If-Match", ifMatch)
+		}
+		return serve(router, r)
+	}
+	assert.Equal(t, http.StatusPreconditionRequired, remove("").Code)
+	assert.Equal(t, http.StatusPreconditionFailed, remove(`"1"`).Code)
+	rec = remove(`"2"`)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	// O ETag da lixeira é o que o restore espera no If-Match
+	assert.Equal(t, `"3"`, rec.Header().Get("ETag"))
+	_, err := productDB.FindByID(product.ID.String())
+	assert.Error(t, err)
+}
+
+func TestUpdateProductWithoutRequiredIfMatch(t *testing.T) {
+	productDB, router := newTestProductHandler(t, ProductHandlerConfig{RequireIfMatch: false})
+	product := createTestProduct(t, productDB, "sofá")
+
+	// Desligado, a alteração sem If-Match passa, mas um ETag antigo continua sendo recusado
+	body := `{"name": "sofá retrátil", "price": {"amount": "10", "currency": "BRL"}}`
+	rec := serve(router, newRequest(http.MethodPut, "/products/"+product.ID.String(), body, testOwnerID.String(), entity.RoleEditor))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+
+	r := newRequest(http.MethodPut, "/products/"+product.ID.String(), body, testOwnerID.String(), entity.RoleEditor)
+	r.Header.Set("If-Match", `"1"`)
+	assert.Equal(t, http.StatusPreconditionFailed, serve(router, r).Code)
+}
+
+// patchProduct envia o merge patch com o ETag atual do produto
+func patchProduct(router http.Handler, id, contentType, body string) *httptest.ResponseRecorder {
+	r := newRequest(http.MethodPatch, "/products/"+id, body, testOwnerID.String(), entity.RoleEditor)
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	return serve(router, r)
+}
+
+// fieldErrors devolve os campos e códigos do problema validation_failed
+func fieldErrors(t *testing.T, rec *httptest.ResponseRecorder) map[string]string {
+	t.Helper()
+	p := decodeProblem(t, rec)
+	assert.Equal(t, "validation_failed", p.Code)
+	fields := map[string]string{}
+	for _, fe := range p.Errors {
+		fields[fe.Field] = fe.Code
+	}
+	return fields
+}
+
+func TestPatchProductContentType(t *testing.T) {
+	productDB, router := newTestProductHandler(t, ProductHandlerConfig{})
+	product := createTestProduct(t, productDB, "cadeira")
+
+	for _, contentType := range []string{"", "application/json", "text/plain"} {
+		rec := patchProduct(router, product.ID.String(), contentType, `{"stock": 1}`)
+		assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code, contentType)
+		assert.Equal(t, "unsupported_media_type", decodeProblem(t, rec).Code)
+	}
+	// Parâmetros do media type são aceitos
+	rec := patchProduct(router, product.ID.String(), "application/merge-patch+json; charset=utf-8", `{"stock": 1}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestPatchProductMergePatch(t *testing.T) {
+	productDB, router := newTestProductHandler(t, ProductHandlerConfig{})
+	product := createTestProduct(t, productDB, "cadeira",
+		entity.WithDescription("ergonômica"), entity.WithSKU("CAD-1"), entity.WithStock(4), entity.WithTags("escritório"))
+	id := product.ID.String()
+
+	// Só os campos enviados mudam e o null remove os opcionais
+	rec := patchProduct(router, id, "application/merge-patch+json", `{"stock": 9, "description": null, "sku": null, "tags": null}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var patched entity.Product
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&patched))
+	assert.Equal(t, int64(9), patched.Stock)
+	assert.Equal(t, int64(2), patched.Version)
+	assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+
+	saved, err := productDB.FindByID(id)
+	assert.NoError(t, err)
+	assert.Equal(t, "cadeira", saved.Name)
+	assert.Equal(t, product.Price, saved.Price)
+	assert.Equal(t, int64(9), saved.Stock)
+	assert.Empty(t, saved.Description)
+	assert.Nil(t, saved.SKU)
+	assert.Empty(t, saved.Tags)
+	assert.Equal(t, testOwnerID, *saved.OwnerID)
+	assert.True(t, product.CreatedAt.Equal(saved.CreatedAt))
+
+	// O preço é um objeto, o merge patch altera só o valor e mantém a moeda
+	rec = patchProduct(router, id, "application/merge-patch+json", `{"price": {"amount": "12.50"}}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	saved, _ = productDB.FindByID(id)
+	assert.Equal(t, entityPkg.MustParseMoney("12.50", "BRL"), saved.Price)
+}
+
+func TestPatchProductRejectsImmutableFields(t *testing.T) {
+	productDB, router := newTestProductHandler(t, ProductHandlerConfig{})
+	product := createTestProduct(t, productDB, "cadeira")
+	id := product.ID.String()
+
+	cases := map[string]string{
+		"id":         `{"id": "` + entityPkg.NewID().String() + `"}`,
+		"version":    `{"version": 5}`,
+		"created_at": `{"created_at": "2020-01-01T00:00:00Z"}`,
+		"owner_id":   `{"owner_id": null}`,
+	}
+	for field, body := range cases {
+		rec := patchProduct(router, id, "application/merge-patch+json", body)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, field)
+		assert.Equal(t, map[string]string{field: entity.CodeImmutable}, fieldErrors(t, rec), field)
+	}
+
+	// Nada foi gravado
+	saved, err := productDB.FindByID(id)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), saved.Version)
+	assert.Equal(t, product.ID, saved.ID)
+}
+
+func TestPatchProductInvalidResult(t *testing.T) {
+	productDB, router := newTestProductHandler(t, ProductHandlerConfig{})
+	product := createTestProduct(t, productDB, "cadeira")
+	id := product.ID.String()
+
+	// O produto resultante passa pelas validações do cadastro e todos os campos inválidos voltam juntos
+	rec := patchProduct(router, id, "application/merge-patch+json", `{"name": null, "stock": -1, "status": "sold"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	fields := fieldErrors(t, rec)
+	assert.Contains(t, fields, "name")
+	assert.Contains(t, fields, "stock")
+	assert.Contains(t, fields, "status")
+
+	// Campo desconhecido e JSON inválido são erros do body
+	rec = patchProduct(router, id, "application/merge-patch+json", `{"colour": "red"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = patchProduct(router, id, "application/merge-patch+json", `{"name": `)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	saved, err := productDB.FindByID(id)
+	assert.NoError(t, err)
+	assert.Equal(t, "cadeira", saved.Name)
+	assert.Equal(t, int64(1), saved.Version)
+}
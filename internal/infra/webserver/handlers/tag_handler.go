@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/waanvieira/api-users/internal/entity"
+	"github.com/waanvieira/api-users/internal/infra/database"
+	"github.com/waanvieira/api-users/internal/infra/webserver/problem"
+)
+
+type TagHandler struct {
+	TagDB database.TagInterface
+}
+
+func NewTagHandler(db database.TagInterface) *TagHandler {
+	return &TagHandler{
+		TagDB: db,
+	}
+}
+
+// GetTags godoc
+// @Summary      List tags
+// @Description  List the tags in use with how many products have each one, most used first
+// @Tags         tags
+// @Produce      json
+// @Success      200         {array}   entity.TagCount
+// @Failure      401         {object}  problem.Problem
+// @Failure      500         {object}  problem.Problem
+// @Router       /tags [get]
+// @Security ApiKeyAuth
+func (h *TagHandler) GetTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.TagDB.FindAllWithCount()
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	if tags == nil {
+		tags = []entity.TagCount{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tags)
+}
@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/go-chi/chi"
@@ -10,66 +12,230 @@ import (
 	"github.com/waanvieira/api-users/internal/dto"
 	user_dto "github.com/waanvieira/api-users/internal/dto/users"
 	"github.com/waanvieira/api-users/internal/entity"
+	"github.com/waanvieira/api-users/internal/infra/auth"
 	"github.com/waanvieira/api-users/internal/infra/database"
+	"github.com/waanvieira/api-users/internal/infra/webserver/problem"
+	entityPkg "github.com/waanvieira/api-users/pkg/entity"
+	"gorm.io/gorm"
 )
 
-// Struct que representa como vamos retornar um erro caso tenha algum problema
-type Error struct {
-	Message string `json:"message"`
+// UserHandlerConfig são as opções do handler que vêm do .env
+type UserHandlerConfig struct {
+	// Tamanho da página do GET /users sem ?limit=, 0 usa 20
+	DefaultLimit int
+	// Maior ?limit= aceito, 0 não limita
+	MaxLimit int
 }
+
 type UserHandler struct {
-	UserDB database.UserInterface
+	UserDB         database.UserInterface
+	RefreshTokenDB database.RefreshTokenInterface
+	RevokedTokenDB database.RevokedTokenInterface
+	Config         UserHandlerConfig
 }
 
 // Aqui é basicamente o nosso construtor, indicando que estamos recebendo a interface, e não a classe concreta
 // Isso é inversão de dependencia
-func NewUserHandler(db database.UserInterface) *UserHandler {
+func NewUserHandler(db database.UserInterface, refreshTokenDB database.RefreshTokenInterface, revokedTokenDB database.RevokedTokenInterface, config UserHandlerConfig) *UserHandler {
 	return &UserHandler{
-		UserDB: db,
+		UserDB:         db,
+		RefreshTokenDB: refreshTokenDB,
+		RevokedTokenDB: revokedTokenDB,
+		Config:         config,
 	}
 }
 
 // GetJWT godoc
 // @Summary      Get a user JWT
-// @Description  Get a user JWT
+// @Description  Get a user JWT and a refresh token
 // @Tags         users
 // @Accept       json
 // @Produce      json
 // @Param        request   body     user_dto.GetJWTInput  true  "user credentials"
 // @Success      200  {object}  user_dto.GetJWTOutput
-// @Failure      404  {object}  Error
-// @Failure      500  {object}  Error
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem  "unknown email or wrong password"
+// @Failure      500  {object}  problem.Problem
 // @Router       /users/generate_token [post]
 func (h *UserHandler) GetJWT(w http.ResponseWriter, r *http.Request) {
-	jwt := r.Context().Value("jwt").(*jwtauth.JWTAuth)
-	jwtExpiresIn := r.Context().Value("JwtExperesIn").(int)
 	var user user_dto.GetJWTInput
 	err := json.NewDecoder(r.Body).Decode(&user)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		problem.Write(w, r, invalidBody(err))
 		return
 	}
+	// Email não cadastrado responde o mesmo 401 da senha errada, assim o login não revela quais emails existem
+	// O bcrypt roda mesmo assim, senão a resposta mais rápida revelaria o email pelo tempo
 	u, err := h.UserDB.FindByEmail(user.Email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		entity.CompareDummyPassword(user.Password)
+		problem.Write(w, r, entity.ErrInvalidCredentials)
+		return
+	}
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
-		err := Error{Message: err.Error()}
-		json.NewEncoder(w).Encode(err)
+		problem.Write(w, r, err)
 		return
 	}
 	if !u.ValidatePassword(user.Password) {
-		w.WriteHeader(http.StatusUnauthorized)
+		problem.Write(w, r, entity.ErrInvalidCredentials)
 		return
 	}
-	_, tokenString, _ := jwt.Encode(map[string]interface{}{
-		"sub": u.ID.String(),
-		"exp": time.Now().Add(time.Second * time.Duration(jwtExpiresIn)).Unix(),
+	// Cada login começa uma nova família de refresh tokens
+	h.issueTokens(w, r, u, entityPkg.NewID(), nil)
+}
+
+// RefreshToken godoc
+// @Summary      Refresh a user JWT
+// @Description  Exchange a refresh token for a new JWT and a new refresh token. The used refresh token stops working, and reusing it revokes every token issued from the same login
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request   body     user_dto.RefreshTokenInput  true  "refresh token"
+// @Success      200  {object}  user_dto.GetJWTOutput
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /users/refresh [post]
+func (h *UserHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var input user_dto.RefreshTokenInput
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		problem.Write(w, r, invalidBody(err))
+		return
+	}
+	if input.RefreshToken == "" {
+		problem.Write(w, r, problem.New(http.StatusBadRequest, "refresh_token_required", "refresh_token is required"))
+		return
+	}
+
+	current, err := h.RefreshTokenDB.FindByHash(entity.HashRefreshToken(input.RefreshToken))
+	if err != nil {
+		problem.Write(w, r, entity.ErrRefreshTokenInvalid)
+		return
+	}
+	if current.RevokedAt != nil {
+		problem.Write(w, r, entity.ErrRefreshTokenRevoked)
+		return
+	}
+	// Token que já foi trocado sendo usado de novo, alguém pode ter copiado o token então revogamos a família inteira
+	if current.IsUsed() {
+		h.RefreshTokenDB.RevokeFamily(current.FamilyID.String())
+		problem.Write(w, r, entity.ErrRefreshTokenReused)
+		return
+	}
+	if current.IsExpired(time.Now()) {
+		problem.Write(w, r, entity.ErrRefreshTokenExpired)
+		return
+	}
+	// Buscamos o usuário de novo para o token sair com o perfil atual
+	u, err := h.UserDB.FindByID(current.UserID.String())
+	if err != nil {
+		h.RefreshTokenDB.RevokeFamily(current.FamilyID.String())
+		problem.Write(w, r, entity.ErrRefreshTokenInvalid)
+		return
+	}
+	h.issueTokens(w, r, u, current.FamilyID, current)
+}
+
+// Logout godoc
+// @Summary      Logout
+// @Description  Revoke the JWT used in the request and, when informed, every refresh token issued from the same login
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request   body     user_dto.LogoutInput  false  "refresh token"
+// @Success      204
+// @Failure      401  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /users/logout [post]
+// @Security ApiKeyAuth
+func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	token, _, err := jwtauth.FromContext(r.Context())
+	if err != nil || token == nil {
+		problem.Write(w, r, jwtauth.ErrUnauthorized)
+		return
+	}
+
+	// O body é opcional, então ignoramos o erro de body vazio
+	var input user_dto.LogoutInput
+	json.NewDecoder(r.Body).Decode(&input)
+	if input.RefreshToken != "" {
+		refreshToken, err := h.RefreshTokenDB.FindByHash(entity.HashRefreshToken(input.RefreshToken))
+		// Só revogamos se o refresh token for do mesmo usuário do JWT
+		if err == nil && refreshToken.UserID.String() == token.Subject() {
+			if err := h.RefreshTokenDB.RevokeFamily(refreshToken.FamilyID.String()); err != nil {
+				problem.Write(w, r, err)
+				return
+			}
+		}
+	}
+
+	// O jti fica revogado até o horário em que o token iria expirar
+	err = h.RevokedTokenDB.Revoke(token.JwtID(), token.Expiration())
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// issueTokens gera o access token (JWT) e um novo refresh token da família
+// Quando recebe o refresh token atual ele é marcado como trocado na mesma transação em que salvamos o novo
+func (h *UserHandler) issueTokens(w http.ResponseWriter, r *http.Request, user *entity.User, familyID entityPkg.ID, current *entity.RefreshToken) {
+	jwt := r.Context().Value("jwt").(*auth.JWTAuth)
+	jwtExpiresIn := r.Context().Value("JwtExperesIn").(int)
+	jwtRefreshExpiresIn := r.Context().Value("JwtRefreshExpiresIn").(int)
+
+	_, tokenString, err := jwt.Encode(map[string]interface{}{
+		"sub": user.ID.String(),
+		// Perfil do usuário, usado pelo middleware RequireRole
+		"role": user.Role.String(),
+		"exp":  time.Now().Add(time.Second * time.Duration(jwtExpiresIn)).Unix(),
+		// Id único do token, usado para revogar o token no logout
+		"jti": entityPkg.NewID().String(),
 	})
-	accessToken := user_dto.GetJWTOutput{AccessToken: tokenString}
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	refreshToken, refreshTokenString, err := entity.NewRefreshToken(user.ID, familyID, time.Second*time.Duration(jwtRefreshExpiresIn))
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	if current == nil {
+		err = h.RefreshTokenDB.Create(refreshToken)
+	} else {
+		err = h.RefreshTokenDB.Rotate(current, refreshToken)
+	}
+	if errors.Is(err, entity.ErrRefreshTokenReused) {
+		// Outra requisição trocou esse mesmo token antes de nós
+		h.RefreshTokenDB.RevokeFamily(familyID.String())
+		problem.Write(w, r, err)
+		return
+	}
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	accessToken := user_dto.GetJWTOutput{
+		AccessToken:  tokenString,
+		RefreshToken: refreshTokenString,
+		TokenType:    "Bearer",
+		ExpiresIn:    jwtExpiresIn,
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(accessToken)
 }
 
+// invalidBody é o erro devolvido quando o body não é um JSON válido para o DTO
+func invalidBody(err error) *problem.Problem {
+	return problem.New(http.StatusBadRequest, "invalid_body", err.Error())
+}
+
 // Create user godoc
 // @Summary      Create user
 // @Description  Create user
@@ -78,7 +244,10 @@ func (h *UserHandler) GetJWT(w http.ResponseWriter, r *http.Request) {
 // @Produce      json
 // @Param        request     body      dto.CreateUserInput  true  "user request"
 // @Success      201
-// @Failure      500         {object}  Error
+// @Failure      400         {object}  problem.Problem
+// @Failure      409         {object}  problem.Problem
+// @Failure      422         {object}  problem.Problem
+// @Failure      500         {object}  problem.Problem
 // @Router       /users [post]
 // Função que seria o nosso controller, recebe um request e retorna um response
 // Seria um método do nosso controler como store(Resquest $request) {//cadastra no banco de dados}
@@ -86,30 +255,25 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	var user dto.CreateUserInput
 	erro := json.NewDecoder(r.Body).Decode(&user)
 	if erro != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Erro DTO"))
+		problem.Write(w, r, invalidBody(erro))
 		return
 	}
 
-	userDB, _ := h.UserDB.FindByEmail(user.Email)
-	if userDB != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Usuário já registrado"))
+	u, err := entity.NewUser(user.Name, user.Email, user.Password)
+	if err != nil {
+		problem.Write(w, r, err)
 		return
 	}
 
-	u, err := entity.NewUser(user.Name, user.Email, user.Password)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		error := Error{Message: err.Error()}
-		json.NewEncoder(w).Encode(error)
+	userDB, _ := h.UserDB.FindByEmail(u.Email)
+	if userDB != nil {
+		problem.Write(w, r, entity.ErrEmailAlreadyRegistered)
 		return
 	}
+	// Se outra requisição cadastrar o mesmo email entre a busca e o insert o índice único devolve ErrEmailAlreadyRegistered
 	err = h.UserDB.Create(u)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		error := Error{Message: err.Error()}
-		json.NewEncoder(w).Encode(error)
+		problem.Write(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusCreated)
@@ -118,97 +282,194 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 func (h *UserHandler) FindByEmail(w http.ResponseWriter, r *http.Request) {
 	email := chi.URLParam(r, "email")
 	if email == "" {
-		w.WriteHeader(http.StatusBadRequest)
+		problem.Write(w, r, entity.ErrEmailIsRequired)
 		return
 	}
 
 	p, err := h.UserDB.FindByEmail(email)
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
-		w.Write([]byte("Registro não encontrado"))
+		problem.Write(w, r, err)
 		return
 	}
 
 	json.NewEncoder(w).Encode(p)
 }
 
-// func (h *UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
-// 	page := r.URL.Query().Get("page")
-// 	limit := r.URL.Query().Get("limit")
-// 	// Automaticamente quando recebemos parametros ele vem como string o pacote strconv é para converter string em number
-// 	pageInt, err := strconv.Atoi(page)
-// 	if err != nil {
-// 		pageInt = 0
-// 	}
-// 	limitInt, err := strconv.Atoi(limit)
-// 	if err != nil {
-// 		limitInt = 0
-// 	}
-
-// 	sort := r.URL.Query().Get("sort")
-// 	users, err := h.UserDB.FindAll(pageInt, limitInt, sort)
-// 	if err != nil {
-// 		w.WriteHeader(http.StatusInternalServerError)
-// 		return
-// 	}
-// 	w.Header().Set("Content-Type", "application/json")
-// 	w.WriteHeader(http.StatusOK)
-// 	json.NewEncoder(w).Encode(users)
-// }
-
-// func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
-// 	id := chi.URLParam(r, "id")
-// 	if id == "" {
-// 		w.WriteHeader(http.StatusBadRequest)
-// 		return
-// 	}
-
-// 	_, err := h.UserDB.FindByID(id)
-// 	if err != nil {
-// 		w.WriteHeader(http.StatusNotFound)
-// 		w.Write([]byte("Registro não encontrado"))
-// 		return
-// 	}
-
-// 	err = h.UserDB.Delete(id)
-// 	if err != nil {
-// 		w.WriteHeader(http.StatusNotFound)
-// 		w.Write([]byte("Registro não encontrado"))
-// 		return
-// 	}
-
-// 	w.WriteHeader(http.StatusNoContent)
-// 	w.Write([]byte("Registro deletado com sucesso"))
-// }
-
-// func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
-// 	id := chi.URLParam(r, "id")
-// 	if id == "" {
-// 		w.WriteHeader(http.StatusBadRequest)
-// 		return
-// 	}
-// 	var user entity.User
-// 	err := json.NewDecoder(r.Body).Decode(&user)
-// 	if err != nil {
-// 		w.WriteHeader(http.StatusBadRequest)
-// 		return
-// 	}
-// 	user.ID, err = entityPkg.ParseID(id)
-// 	if err != nil {
-// 		w.WriteHeader(http.StatusBadRequest)
-// 		return
-// 	}
-// 	_, err = h.UserDB.FindByID(id)
-// 	if err != nil {
-// 		w.WriteHeader(http.StatusNotFound)
-// 		return
-// 	}
-// 	// Aqui atribuimos a variável como referencia porque o valor já foi setado anteriormente, aqui estamos basicamente atribuindo um novo valor ao err, se mudassemos o valor o nome da variável
-// 	// teriamos que indicar := que seria atribuição do valor na variável err
-// 	err = h.UserDB.Update(&user)
-// 	if err != nil {
-// 		w.WriteHeader(http.StatusInternalServerError)
-// 		return
-// 	}
-// 	w.WriteHeader(http.StatusOK)
-// }
+// GetMe godoc
+// @Summary      Get the authenticated user
+// @Description  Get the user of the JWT
+// @Tags         users
+// @Produce      json
+// @Success      200  {object}  user_dto.UserOutput
+// @Failure      401  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Router       /users/me [get]
+// @Security ApiKeyAuth
+func (h *UserHandler) GetMe(w http.ResponseWriter, r *http.Request) {
+	u, ok := h.me(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(toUserOutput(u))
+}
+
+// UpdateMe godoc
+// @Summary      Update the authenticated user
+// @Description  Update name, email and, when informed, the password of the user of the JWT. A new password revokes every refresh token of the user and the JWT used in the request
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        request   body     user_dto.UpdateUserInput  true  "user request"
+// @Success      200  {object}  user_dto.UserOutput
+// @Failure      400  {object}  problem.Problem
+// @Failure      401  {object}  problem.Problem
+// @Failure      409  {object}  problem.Problem
+// @Failure      422  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /users/me [put]
+// @Security ApiKeyAuth
+func (h *UserHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	var input user_dto.UpdateUserInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		problem.Write(w, r, invalidBody(err))
+		return
+	}
+	u, ok := h.me(w, r)
+	if !ok {
+		return
+	}
+
+	currentEmail := u.Email
+	if err := u.Update(input.Name, input.Email, input.Password); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	if u.Email != currentEmail {
+		// O email é o login, então não pode ser o mesmo de outro usuário
+		if other, _ := h.UserDB.FindByEmail(u.Email); other != nil {
+			problem.Write(w, r, entity.ErrEmailAlreadyRegistered)
+			return
+		}
+	}
+	if err := h.UserDB.Update(u); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	// Com a senha nova os logins feitos com a antiga deixam de valer, inclusive o token desta requisição
+	if input.Password != "" {
+		if err := h.revokeSessions(r, u); err != nil {
+			problem.Write(w, r, err)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(toUserOutput(u))
+}
+
+// DeleteMe godoc
+// @Summary      Delete the authenticated user
+// @Description  Delete the user of the JWT and revoke every refresh token of the user and the JWT used in the request
+// @Tags         users
+// @Success      204
+// @Failure      401  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /users/me [delete]
+// @Security ApiKeyAuth
+func (h *UserHandler) DeleteMe(w http.ResponseWriter, r *http.Request) {
+	u, ok := h.me(w, r)
+	if !ok {
+		return
+	}
+	if err := h.revokeSessions(r, u); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	if err := h.UserDB.Delete(u.ID.String()); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// revokeSessions revoga os refresh tokens de todos os logins do usuário e o JWT usado na requisição
+// Os outros JWTs já emitidos não ficam guardados, eles valem até expirar (JWT_EXPIRESIN)
+func (h *UserHandler) revokeSessions(r *http.Request, u *entity.User) error {
+	if err := h.RefreshTokenDB.RevokeUser(u.ID.String()); err != nil {
+		return err
+	}
+	token, _, _ := jwtauth.FromContext(r.Context())
+	if token == nil || token.JwtID() == "" {
+		return nil
+	}
+	return h.RevokedTokenDB.Revoke(token.JwtID(), token.Expiration())
+}
+
+// GetAllUsers godoc
+// @Summary      List users
+// @Description  List every user, only for admins
+// @Tags         users
+// @Produce      json
+// @Param        page      query     int     false  "page number" default(1)
+// @Param        limit     query     int     false  "page size, same default and maximum as GET /products"
+// @Param        sort      query     string  false  "asc or desc, by name"
+// @Success      200       {array}   user_dto.UserOutput
+// @Failure      400       {object}  problem.Problem  "invalid limit"
+// @Failure      401       {object}  problem.Problem
+// @Failure      403       {object}  problem.Problem
+// @Failure      500       {object}  problem.Problem
+// @Router       /users [get]
+// @Security ApiKeyAuth
+func (h *UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
+	// Sempre paginado: sem page vale a primeira página e o limit segue o padrão e o máximo da configuração
+	pageInt, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || pageInt <= 0 {
+		pageInt = 1
+	}
+	limitInt, err := parseLimit(r, h.Config.DefaultLimit, h.Config.MaxLimit)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	sort := r.URL.Query().Get("sort")
+	users, err := h.UserDB.FindAll(pageInt, limitInt, sort)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	output := make([]user_dto.UserOutput, 0, len(users))
+	for i := range users {
+		output = append(output, toUserOutput(&users[i]))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
+
+// me busca o usuário do token, quando não encontra já responde o erro
+func (h *UserHandler) me(w http.ResponseWriter, r *http.Request) (*entity.User, bool) {
+	userID, _ := currentUser(r)
+	if userID == "" {
+		problem.Write(w, r, jwtauth.ErrUnauthorized)
+		return nil, false
+	}
+	u, err := h.UserDB.FindByID(userID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return nil, false
+	}
+	return u, true
+}
+
+func toUserOutput(u *entity.User) user_dto.UserOutput {
+	return user_dto.UserOutput{
+		ID:    u.ID.String(),
+		Name:  u.Name,
+		Email: u.Email,
+		Role:  u.Role.String(),
+	}
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/stretchr/testify/assert"
+	user_dto "github.com/waanvieira/api-users/internal/dto/users"
+	"github.com/waanvieira/api-users/internal/entity"
+	"github.com/waanvieira/api-users/internal/infra/auth"
+	"github.com/waanvieira/api-users/internal/infra/database"
+	"github.com/waanvieira/api-users/internal/infra/database/migrations"
+	"github.com/waanvieira/api-users/internal/infra/webserver/middlewares"
+
+	// Banco em memória sqlite
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// newTestUserHandler monta as rotas de /users do main sobre o banco em memória criado pelas migrações
+func newTestUserHandler(t *testing.T) (*database.User, http.Handler) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatal(err)
+	}
+	tokenAuth, err := auth.New(auth.Config{Algorithm: "HS256", Secret: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	userDB := database.NewUser(db)
+	revokedTokenDB := database.NewRevokedToken(db)
+	h := NewUserHandler(userDB, database.NewRefreshToken(db), revokedTokenDB, UserHandlerConfig{})
+
+	r := chi.NewRouter()
+	r.Use(middleware.WithValue("jwt", tokenAuth))
+	r.Use(middleware.WithValue("JwtExperesIn", 300))
+	r.Use(middleware.WithValue("JwtRefreshExpiresIn", 3600))
+	r.Post("/users/generate_token", h.GetJWT)
+	r.Post("/users/refresh", h.RefreshToken)
+	r.Group(func(r chi.Router) {
+		r.Use(auth.Verifier(tokenAuth))
+		r.Use(middlewares.Authenticator)
+		r.Use(middlewares.RejectRevokedTokens(revokedTokenDB))
+		r.Get("/users/me", h.GetMe)
+		r.Put("/users/me", h.UpdateMe)
+		r.Delete("/users/me", h.DeleteMe)
+	})
+	return userDB, r
+}
+
+// createTestUser cadastra o usuário direto no repositório
+func createTestUser(t *testing.T, userDB *database.User, email, password string) *entity.User {
+	u, err := entity.NewUser("test", email, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := userDB.Create(u); err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func postJSON(router http.Handler, target string, body interface{}) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(body)
+	r := httptest.NewRequest(http.MethodPost, target, bytes.NewReader(payload))
+	return serve(router, r)
+}
+
+// login devolve o access token e o refresh token do usuário
+func login(t *testing.T, router http.Handler, email, password string) user_dto.GetJWTOutput {
+	t.Helper()
+	rec := postJSON(router, "/users/generate_token", user_dto.GetJWTInput{Email: email, Password: password})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var output user_dto.GetJWTOutput
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&output))
+	return output
+}
+
+// authRequest monta a requisição com o access token no header Authorization
+func authRequest(method, target, body, accessToken string) *http.Request {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer "+accessToken)
+	return r
+}
+
+func TestGetJWTSameResponseForUnknownEmailAndWrongPassword(t *testing.T) {
+	userDB, router := newTestUserHandler(t)
+	createTestUser(t, userDB, "ana@dev.com", "123456")
+
+	timed := func(email string) (*httptest.ResponseRecorder, time.Duration) {
+		start := time.Now()
+		rec := postJSON(router, "/users/generate_token", user_dto.GetJWTInput{Email: email, Password: "errada"})
+		return rec, time.Since(start)
+	}
+	// A primeira chamada gera o hash fixo, fora da medição
+	timed("ninguem@dev.com")
+
+	unknown, unknownTime := timed("ninguem@dev.com")
+	wrong, wrongTime := timed("ana@dev.com")
+	assert.Equal(t, http.StatusUnauthorized, unknown.Code)
+	assert.Equal(t, wrong.Code, unknown.Code)
+	assert.Equal(t, wrong.Header().Get("Content-Type"), unknown.Header().Get("Content-Type"))
+	assert.Equal(t, wrong.Body.String(), unknown.Body.String())
+	p := decodeProblem(t, unknown)
+	assert.Equal(t, "invalid_credentials", p.Code)
+	// Os dois casos passam pelo bcrypt, a margem é larga para não depender da máquina
+	assert.Greater(t, unknownTime, wrongTime/4)
+
+	login(t, router, "ana@dev.com", "123456")
+}
+
+// refresh tenta trocar o refresh token e devolve a resposta
+func refresh(router http.Handler, refreshToken string) *httptest.ResponseRecorder {
+	return postJSON(router, "/users/refresh", user_dto.RefreshTokenInput{RefreshToken: refreshToken})
+}
+
+func TestUpdateMePasswordRevokesSessions(t *testing.T) {
+	userDB, router := newTestUserHandler(t)
+	createTestUser(t, userDB, "ana@dev.com", "123456")
+	first := login(t, router, "ana@dev.com", "123456")
+	second := login(t, router, "ana@dev.com", "123456")
+
+	// Sem senha nova nada é revogado
+	rec := serve(router, authRequest(http.MethodPut, "/users/me", `{"name":"Ana","email":"ana@dev.com"}`, first.AccessToken))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, http.StatusOK, serve(router, authRequest(http.MethodGet, "/users/me", "", first.AccessToken)).Code)
+
+	rec = serve(router, authRequest(http.MethodPut, "/users/me", `{"name":"Ana","email":"ana@dev.com","password":"nova123"}`, first.AccessToken))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = serve(router, authRequest(http.MethodGet, "/users/me", "", first.AccessToken))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, "token_revoked", decodeProblem(t, rec).Code)
+	for _, output := range []user_dto.GetJWTOutput{first, second} {
+		rec = refresh(router, output.RefreshToken)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, "refresh_token_revoked", decodeProblem(t, rec).Code)
+	}
+
+	// O login com a senha nova continua funcionando
+	current := login(t, router, "ana@dev.com", "nova123")
+	assert.Equal(t, http.StatusOK, serve(router, authRequest(http.MethodGet, "/users/me", "", current.AccessToken)).Code)
+	assert.Equal(t, http.StatusOK, refresh(router, current.RefreshToken).Code)
+}
+
+func TestDeleteMeRevokesSessions(t *testing.T) {
+	userDB, router := newTestUserHandler(t)
+	createTestUser(t, userDB, "ana@dev.com", "123456")
+	output := login(t, router, "ana@dev.com", "123456")
+
+	rec := serve(router, authRequest(http.MethodDelete, "/users/me", "", output.AccessToken))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = serve(router, authRequest(http.MethodGet, "/users/me", "", output.AccessToken))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, "token_revoked", decodeProblem(t, rec).Code)
+	rec = refresh(router, output.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, "refresh_token_revoked", decodeProblem(t, rec).Code)
+}
@@ -0,0 +1,26 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/waanvieira/api-users/internal/infra/webserver/problem"
+)
+
+// Authenticator faz o mesmo que o jwtauth.Authenticator, mas responde os erros no formato problem+json
+// Precisa ficar depois do auth.Verifier, que deixa o token e o erro no contexto
+func Authenticator(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, _, err := jwtauth.FromContext(r.Context())
+		if err != nil {
+			problem.Write(w, r, err)
+			return
+		}
+		if token == nil || jwt.Validate(token) != nil {
+			problem.Write(w, r, jwtauth.ErrUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,43 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/waanvieira/api-users/internal/infra/database"
+	"github.com/waanvieira/api-users/internal/infra/webserver/problem"
+)
+
+var (
+	ErrTokenWithoutJTI = problem.New(http.StatusUnauthorized, "token_without_jti", "token has no jti")
+	ErrTokenRevoked    = problem.New(http.StatusUnauthorized, "token_revoked", "token has been revoked")
+)
+
+// RejectRevokedTokens precisa ficar depois do jwtauth.Authenticator, nesse ponto o token já foi validado
+// Aqui verificamos apenas se o jti do token foi revogado (logout por exemplo)
+func RejectRevokedTokens(store database.RevokedTokenInterface) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, _, err := jwtauth.FromContext(r.Context())
+			if err != nil || token == nil {
+				problem.Write(w, r, jwtauth.ErrUnauthorized)
+				return
+			}
+			// Todo token que geramos tem jti, sem ele não teríamos como revogar então não aceitamos
+			if token.JwtID() == "" {
+				problem.Write(w, r, ErrTokenWithoutJTI)
+				return
+			}
+			revoked, err := store.IsRevoked(token.JwtID())
+			if err != nil {
+				problem.Write(w, r, err)
+				return
+			}
+			if revoked {
+				problem.Write(w, r, ErrTokenRevoked)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/waanvieira/api-users/internal/entity"
+	"github.com/waanvieira/api-users/internal/infra/webserver/problem"
+)
+
+var ErrForbidden = problem.New(http.StatusForbidden, "forbidden", "you do not have permission to access this resource")
+
+// RequireRole libera a rota apenas se o perfil do token (claim "role") incluir um dos perfis informados
+// Precisa ficar depois do jwtauth.Authenticator, ex: r.With(middlewares.RequireRole(entity.RoleEditor)).Post(...)
+func RequireRole(roles ...entity.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role := RoleFromContext(r)
+			for _, required := range roles {
+				if role.Includes(required) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			problem.Write(w, r, ErrForbidden)
+		})
+	}
+}
+
+// RoleFromContext pega o perfil das claims do token validado pelo jwtauth
+func RoleFromContext(r *http.Request) entity.Role {
+	_, claims, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		return ""
+	}
+	role, _ := claims["role"].(string)
+	return entity.Role(role)
+}
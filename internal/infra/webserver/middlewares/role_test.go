@@ -0,0 +1,45 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/stretchr/testify/assert"
+	"github.com/waanvieira/api-users/internal/entity"
+)
+
+func requestWithRole(role string) *http.Request {
+	ja := jwtauth.New("HS256", []byte("secret"), nil)
+	claims := map[string]interface{}{"sub": "user-id"}
+	if role != "" {
+		claims["role"] = role
+	}
+	token, _, _ := ja.Encode(claims)
+	r := httptest.NewRequest(http.MethodPost, "/products", nil)
+	return r.WithContext(jwtauth.NewContext(r.Context(), token, nil))
+}
+
+func TestRequireRole(t *testing.T) {
+	handler := RequireRole(entity.RoleEditor)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	cases := map[string]int{
+		"admin":  http.StatusCreated,
+		"editor": http.StatusCreated,
+		"viewer": http.StatusForbidden,
+		"":       http.StatusForbidden,
+	}
+	for role, status := range cases {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, requestWithRole(role))
+		assert.Equal(t, status, rec.Code, role)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, requestWithRole("viewer"))
+	assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "permission")
+}
@@ -0,0 +1,145 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/waanvieira/api-users/internal/entity"
+	"github.com/waanvieira/api-users/internal/infra/auth"
+	"github.com/waanvieira/api-users/internal/infra/database"
+	entityPkg "github.com/waanvieira/api-users/pkg/entity"
+	"github.com/waanvieira/api-users/pkg/filter"
+	"gorm.io/gorm"
+)
+
+const ContentType = "application/problem+json"
+
+// Problem é o corpo dos erros da api no formato da RFC 7807 (application/problem+json)
+type Problem struct {
+	Type   string `json:"type" example:"about:blank"`
+	Title  string `json:"title" example:"Not Found"`
+	Status int    `json:"status" example:"404"`
+	Detail string `json:"detail,omitempty" example:"record not found"`
+	// Caminho da requisição que gerou o erro
+	Instance string `json:"instance,omitempty" example:"/products/4f1c2a8e-1d2b-4c4f-9a8e-3b2d1c0f9e7a"`
+	// Código estável para o cliente tratar o erro sem depender da mensagem
+	Code string `json:"code" example:"not_found"`
+	// Campos que falharam na validação, apenas no code "validation_failed"
+	Errors []entity.FieldError `json:"errors,omitempty"`
+	// Posição (a partir de 1) e trecho do ?filter= com problema, apenas no code "invalid_filter"
+	Position int    `json:"position,omitempty" example:"7"`
+	Token    string `json:"token,omitempty" example:"greater"`
+}
+
+// New cria um problema para erros que não vêm do domínio, ex: body inválido
+func New(status int, code, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+func (p *Problem) Error() string {
+	return p.Detail
+}
+
+type mapping struct {
+	err    error
+	status int
+	code   string
+}
+
+// mappings liga os erros do domínio, do banco e do jwt ao status e ao código devolvido para o cliente
+// A ordem importa apenas quando um erro embrulha outro, o primeiro que bater vale
+var mappings = []mapping{
+	{entity.ErrIDIsRequired, http.StatusBadRequest, "id_required"},
+	{entity.ErrInvalidID, http.StatusBadRequest, "invalid_id"},
+	{entity.ErrNameIsRequired, http.StatusUnprocessableEntity, "name_required"},
+	{entity.ErrInvalidName, http.StatusUnprocessableEntity, "invalid_name"},
+	{entity.ErrPriceIsRequired, http.StatusUnprocessableEntity, "price_required"},
+	{entity.ErrInvalidPrice, http.StatusUnprocessableEntity, "invalid_price"},
+	{entityPkg.ErrInvalidAmount, http.StatusUnprocessableEntity, "invalid_amount"},
+	{entityPkg.ErrInvalidScale, http.StatusUnprocessableEntity, "invalid_price_scale"},
+	{entityPkg.ErrUnsupportedCurrency, http.StatusUnprocessableEntity, "unsupported_currency"},
+	{entity.ErrDescriptionTooLong, http.StatusUnprocessableEntity, "description_too_long"},
+	{entity.ErrInvalidSKU, http.StatusUnprocessableEntity, "invalid_sku"},
+	{entity.ErrInvalidStock, http.StatusUnprocessableEntity, "invalid_stock"},
+	{entity.ErrInvalidStatus, http.StatusUnprocessableEntity, "invalid_status"},
+	{entity.ErrInvalidTag, http.StatusUnprocessableEntity, "invalid_tag"},
+	{entity.ErrTooManyTags, http.StatusUnprocessableEntity, "too_many_tags"},
+	{entity.ErrSKUAlreadyExists, http.StatusConflict, "sku_already_exists"},
+	{entity.ErrEmailIsRequired, http.StatusUnprocessableEntity, "email_required"},
+	{entity.ErrInvalidRole, http.StatusUnprocessableEntity, "invalid_role"},
+	{entity.ErrEmailAlreadyRegistered, http.StatusConflict, "email_already_registered"},
+	{entity.ErrCategoryNameTooLong, http.StatusUnprocessableEntity, "category_name_too_long"},
+	{entity.ErrCategoryCycle, http.StatusUnprocessableEntity, "category_cycle"},
+	{entity.ErrCategoryParentNotFound, http.StatusUnprocessableEntity, "category_parent_not_found"},
+	{entity.ErrCategoryNotFound, http.StatusUnprocessableEntity, "category_not_found"},
+	{entity.ErrCategoryHasChildren, http.StatusConflict, "category_has_children"},
+	{entity.ErrNotProductOwner, http.StatusForbidden, "not_product_owner"},
+	{entity.ErrVersionConflict, http.StatusPreconditionFailed, "version_conflict"},
+	{entity.ErrInvalidCredentials, http.StatusUnauthorized, "invalid_credentials"},
+	{entity.ErrRefreshTokenInvalid, http.StatusUnauthorized, "refresh_token_invalid"},
+	{entity.ErrRefreshTokenExpired, http.StatusUnauthorized, "refresh_token_expired"},
+	{entity.ErrRefreshTokenRevoked, http.StatusUnauthorized, "refresh_token_revoked"},
+	{entity.ErrRefreshTokenReused, http.StatusUnauthorized, "refresh_token_reused"},
+	{database.ErrInvalidSort, http.StatusBadRequest, "invalid_sort"},
+	{database.ErrInvalidCursor, http.StatusBadRequest, "invalid_cursor"},
+	{database.ErrInvalidSearch, http.StatusBadRequest, "invalid_search"},
+	{gorm.ErrRecordNotFound, http.StatusNotFound, "not_found"},
+	{gorm.ErrDuplicatedKey, http.StatusConflict, "duplicated_key"},
+	{jwtauth.ErrNoTokenFound, http.StatusUnauthorized, "token_missing"},
+	{jwtauth.ErrExpired, http.StatusUnauthorized, "token_expired"},
+	{jwtauth.ErrUnauthorized, http.StatusUnauthorized, "unauthorized"},
+	{jwtauth.ErrNBFInvalid, http.StatusUnauthorized, "unauthorized"},
+	{jwtauth.ErrIATInvalid, http.StatusUnauthorized, "unauthorized"},
+	{jwtauth.ErrAlgoInvalid, http.StatusUnauthorized, "unauthorized"},
+	{auth.ErrUnknownKeyID, http.StatusUnauthorized, "unauthorized"},
+}
+
+// From converte o erro no problema correspondente, erros desconhecidos viram 500 sem expor a mensagem
+func From(err error) *Problem {
+	var p *Problem
+	if errors.As(err, &p) {
+		copied := *p
+		return &copied
+	}
+	// Antes dos mapeamentos, o errors.Is também encontraria os erros de cada campo
+	var validation entity.ValidationErrors
+	if errors.As(err, &validation) {
+		p := New(http.StatusUnprocessableEntity, "validation_failed", "one or more fields are invalid")
+		p.Errors = validation
+		return p
+	}
+	var filterErr *filter.Error
+	if errors.As(err, &filterErr) {
+		p := New(http.StatusBadRequest, "invalid_filter", filterErr.Error())
+		p.Position = filterErr.Pos
+		p.Token = filterErr.Token
+		return p
+	}
+	for _, m := range mappings {
+		if errors.Is(err, m.err) {
+			return New(m.status, m.code, err.Error())
+		}
+	}
+	return New(http.StatusInternalServerError, "internal_error", "internal server error")
+}
+
+// Write responde o erro como application/problem+json, erros 500 são registrados no log com a mensagem original
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	p := From(err)
+	p.Instance = r.URL.Path
+	if p.Status >= http.StatusInternalServerError {
+		log.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+	}
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
@@ -0,0 +1,74 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/waanvieira/api-users/internal/entity"
+	entityPkg "github.com/waanvieira/api-users/pkg/entity"
+	"github.com/waanvieira/api-users/pkg/filter"
+	"gorm.io/gorm"
+)
+
+func TestFrom(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{entity.ErrInvalidPrice, http.StatusUnprocessableEntity, "invalid_price"},
+		{entity.ErrNameIsRequired, http.StatusUnprocessableEntity, "name_required"},
+		{gorm.ErrRecordNotFound, http.StatusNotFound, "not_found"},
+		{gorm.ErrDuplicatedKey, http.StatusConflict, "duplicated_key"},
+		// Erros embrulhados também são encontrados
+		{fmt.Errorf("find product: %w", gorm.ErrRecordNotFound), http.StatusNotFound, "not_found"},
+		{New(http.StatusBadRequest, "invalid_body", "unexpected EOF"), http.StatusBadRequest, "invalid_body"},
+		{errors.New("connection refused"), http.StatusInternalServerError, "internal_error"},
+	}
+	for _, c := range cases {
+		p := From(c.err)
+		assert.Equal(t, c.status, p.Status, c.err.Error())
+		assert.Equal(t, c.code, p.Code, c.err.Error())
+		assert.Equal(t, http.StatusText(c.status), p.Title)
+		assert.Equal(t, "about:blank", p.Type)
+	}
+	// A mensagem de erros desconhecidos não vai para o cliente
+	assert.NotContains(t, From(errors.New("connection refused")).Detail, "refused")
+}
+
+func TestFromValidationErrors(t *testing.T) {
+	_, err := entity.NewProduct("", entityPkg.MustParseMoney("-1", "BRL"))
+	p := From(err)
+	assert.Equal(t, http.StatusUnprocessableEntity, p.Status)
+	assert.Equal(t, "validation_failed", p.Code)
+
+	body, _ := json.Marshal(p)
+	assert.Contains(t, string(body), `"errors":[{"field":"name","code":"required"},{"field":"price","code":"invalid"}]`)
+}
+
+func TestFromFilterError(t *testing.T) {
+	_, err := filter.Parse("price greater 10")
+	p := From(err)
+	assert.Equal(t, http.StatusBadRequest, p.Status)
+	assert.Equal(t, "invalid_filter", p.Code)
+	assert.Equal(t, 7, p.Position)
+	assert.Equal(t, "greater", p.Token)
+}
+
+func TestWrite(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Write(rec, httptest.NewRequest(http.MethodGet, "/products/123", nil), gorm.ErrRecordNotFound)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+	var body Problem
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "not_found", body.Code)
+	assert.Equal(t, "/products/123", body.Instance)
+	assert.Equal(t, "record not found", body.Detail)
+}
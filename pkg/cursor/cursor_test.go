@@ -0,0 +1,46 @@
+package cursor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type position struct {
+	Sort string `json:"s"`
+	ID   string `json:"id"`
+}
+
+func TestEncodeDecode(t *testing.T) {
+	signer := NewSigner([]byte("secret"))
+	token, err := signer.Encode(position{Sort: "-price", ID: "42"})
+	assert.NoError(t, err)
+	assert.NotContains(t, token, "=")
+	assert.NotContains(t, token, "/")
+
+	var decoded position
+	assert.NoError(t, signer.Decode(token, &decoded))
+	assert.Equal(t, position{Sort: "-price", ID: "42"}, decoded)
+}
+
+func TestDecodeRejectsTamperedCursors(t *testing.T) {
+	signer := NewSigner([]byte("secret"))
+	token, _ := signer.Encode(position{Sort: "name", ID: "1"})
+	payload, signature, _ := strings.Cut(token, ".")
+	forged, _ := signer.Encode(position{Sort: "name", ID: "2"})
+	forgedPayload, _, _ := strings.Cut(forged, ".")
+
+	var decoded position
+	for _, invalid := range []string{
+		"",
+		"abc",
+		payload,
+		forgedPayload + "." + signature,
+		payload + "." + signature + "x",
+	} {
+		assert.ErrorIs(t, signer.Decode(invalid, &decoded), ErrInvalid, invalid)
+	}
+	// A cursor signed with another key is rejected
+	assert.ErrorIs(t, NewSigner([]byte("other")).Decode(token, &decoded), ErrInvalid)
+}
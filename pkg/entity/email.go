@@ -3,6 +3,9 @@ package entity
 import (
 	"errors"
 	"regexp"
+	"strings"
+
+	"golang.org/x/net/idna"
 )
 
 // EmailAddress errors
@@ -10,23 +13,59 @@ var (
 	ErrInvalidEmailAddress = errors.New("Not a valid email address")
 )
 
-// EmailAddress represents a valid email address
+// Limits from RFC 5321
+const (
+	maxEmailLength     = 254
+	maxLocalPartLength = 64
+)
+
+var (
+	// Dot-atom local part, without quoted strings
+	localPartPattern = regexp.MustCompile("^[a-z0-9!#$%&'*+/=?^_`{|}~-]+(\\.[a-z0-9!#$%&'*+/=?^_`{|}~-]+)*$")
+	// TLDs are alphabetic (any length) or punycode
+	tldPattern = regexp.MustCompile(`^([a-z]{2,63}|xn--[a-z0-9-]{1,59})$`)
+	// Validates the ASCII form, so internationalized domains must be converted first
+	domainProfile = idna.New(idna.MapForLookup(), idna.ValidateLabels(true), idna.VerifyDNSLength(true), idna.StrictDomainName(true))
+)
+
+// EmailAddress represents a valid, normalized email address
 type EmailAddress struct {
 	value string
 }
 
-// NewEmailAddress creates a new email address
-func NewEmailAddress(email string) EmailAddress {
-	var n EmailAddress
-	match, _ := regexp.MatchString(`^[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,4}$`, email)
-	if !match {
-		panic("Email invalid")
-		// return n, ErrInvalidEmailAddress
+// NewEmailAddress validates and normalizes an email address.
+// Surrounding whitespace is trimmed, the address is lower-cased and
+// internationalized domains are kept in their canonical Unicode form,
+// so two spellings of the same address produce the same value.
+func NewEmailAddress(email string) (EmailAddress, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	at := strings.LastIndex(email, "@")
+	if at <= 0 || at == len(email)-1 {
+		return EmailAddress{}, ErrInvalidEmailAddress
+	}
+	local, domain := email[:at], email[at+1:]
+	if len(local) > maxLocalPartLength || !localPartPattern.MatchString(local) {
+		return EmailAddress{}, ErrInvalidEmailAddress
 	}
-	n.value = email
 
-	// return n, nil
-	return n
+	ascii, err := domainProfile.ToASCII(domain)
+	if err != nil {
+		return EmailAddress{}, ErrInvalidEmailAddress
+	}
+	labels := strings.Split(ascii, ".")
+	if len(labels) < 2 || !tldPattern.MatchString(labels[len(labels)-1]) {
+		return EmailAddress{}, ErrInvalidEmailAddress
+	}
+	unicode, err := domainProfile.ToUnicode(ascii)
+	if err != nil {
+		return EmailAddress{}, ErrInvalidEmailAddress
+	}
+
+	value := local + "@" + unicode
+	if len(local)+1+len(ascii) > maxEmailLength {
+		return EmailAddress{}, ErrInvalidEmailAddress
+	}
+	return EmailAddress{value: value}, nil
 }
 
 // String returns string representation of the email address
@@ -35,7 +74,6 @@ func (n EmailAddress) String() string {
 }
 
 // Equals checks that two email addresses are the same
-// func (n EmailAddress) Equals(value Value) bool {
-// 	otherEmailAddress, ok := value.(EmailAddress)
-// 	return ok && n.value == otherEmailAddress.value
-// }
+func (n EmailAddress) Equals(other EmailAddress) bool {
+	return n.value == other.value
+}
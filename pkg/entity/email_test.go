@@ -0,0 +1,52 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewEmailAddressNormalizes(t *testing.T) {
+	cases := map[string]string{
+		"user@example.com":             "user@example.com",
+		"  User.Name@Example.COM \n":   "user.name@example.com",
+		"first+tag@sub.example.io":     "first+tag@sub.example.io",
+		"dev@company.technology":       "dev@company.technology",
+		"o'connor@example.photography": "o'connor@example.photography",
+		"user@bücher.de":               "user@bücher.de",
+		"user@xn--bcher-kva.de":        "user@bücher.de",
+		"user@BÜCHER.de":               "user@bücher.de",
+		"user@example.xn--p1ai":        "user@example.рф",
+	}
+	for input, expected := range cases {
+		email, err := NewEmailAddress(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, expected, email.String(), input)
+	}
+
+	a, _ := NewEmailAddress("USER@Example.com")
+	b, _ := NewEmailAddress("user@example.com")
+	assert.True(t, a.Equals(b))
+}
+
+func TestNewEmailAddressRejectsInvalid(t *testing.T) {
+	invalid := []string{
+		"",
+		"user",
+		"@example.com",
+		"user@",
+		"user@localhost",
+		"user@example.c",
+		"user@example.123",
+		".user@example.com",
+		"user..name@example.com",
+		"user name@example.com",
+		"user@exa_mple.com",
+		"user@-example.com",
+		"user@example..com",
+	}
+	for _, input := range invalid {
+		_, err := NewEmailAddress(input)
+		assert.ErrorIs(t, err, ErrInvalidEmailAddress, input)
+	}
+}
@@ -0,0 +1,208 @@
+package entity
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Money errors
+var (
+	ErrInvalidAmount       = errors.New("amount must be a decimal number, e.g. \"10.50\"")
+	ErrUnsupportedCurrency = errors.New("unsupported currency, use BRL, EUR, GBP, JPY or USD")
+	ErrInvalidScale        = errors.New("amount has more decimal places than the currency allows")
+	ErrCurrencyMismatch    = errors.New("amounts have different currencies")
+)
+
+// DefaultCurrency is used when the JSON does not inform the currency
+const DefaultCurrency = "BRL"
+
+// Decimal places (ISO 4217 minor unit) of each supported currency
+var currencyScales = map[string]int{
+	"BRL": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"JPY": 0,
+	"USD": 2,
+}
+
+var amountPattern = regexp.MustCompile(`^(-?)([0-9]+)(?:\.([0-9]+))?$`)
+
+// Money is an amount stored in the minor unit of the currency (cents for BRL),
+// so totals are exact integers instead of floats
+type Money struct {
+	Amount   int64  `json:"amount" gorm:"not null;default:0" swaggertype:"string" example:"10.50"`
+	Currency string `json:"currency" gorm:"size:3;not null;default:BRL" example:"BRL"`
+}
+
+// IsSupportedCurrency checks that the ISO 4217 code is accepted
+func IsSupportedCurrency(currency string) bool {
+	_, ok := currencyScales[currency]
+	return ok
+}
+
+// SupportedCurrencies returns the accepted ISO 4217 codes in alphabetical order
+func SupportedCurrencies() []string {
+	currencies := make([]string, 0, len(currencyScales))
+	for currency := range currencyScales {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	return currencies
+}
+
+// CurrencyScale returns how many decimal places the currency has, 0 when it is not supported
+func CurrencyScale(currency string) int {
+	return currencyScales[currency]
+}
+
+// NewMoney creates money from an amount already in minor units
+func NewMoney(amount int64, currency string) (Money, error) {
+	m := Money{Amount: amount, Currency: strings.ToUpper(strings.TrimSpace(currency))}
+	if err := m.Validate(); err != nil {
+		return Money{}, err
+	}
+	return m, nil
+}
+
+// ParseMoney converts a decimal string like "10.50" into minor units of the currency.
+// More decimal places than the currency has is an error, the amount is never rounded.
+func ParseMoney(amount, currency string) (Money, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	scale, ok := currencyScales[currency]
+	if !ok {
+		return Money{}, ErrUnsupportedCurrency
+	}
+	parts := amountPattern.FindStringSubmatch(strings.TrimSpace(amount))
+	if parts == nil {
+		return Money{}, ErrInvalidAmount
+	}
+	sign, integer, fraction := parts[1], parts[2], parts[3]
+	if len(fraction) > scale {
+		return Money{}, ErrInvalidScale
+	}
+	fraction += strings.Repeat("0", scale-len(fraction))
+	minor, err := strconv.ParseInt(sign+integer+fraction, 10, 64)
+	if err != nil {
+		return Money{}, ErrInvalidAmount
+	}
+	return Money{Amount: minor, Currency: currency}, nil
+}
+
+// MustParseMoney is like ParseMoney but panics on error, for constants and tests
+func MustParseMoney(amount, currency string) Money {
+	m, err := ParseMoney(amount, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// Validate checks that the currency is supported
+func (m Money) Validate() error {
+	if !IsSupportedCurrency(m.Currency) {
+		return ErrUnsupportedCurrency
+	}
+	return nil
+}
+
+// Add sums two amounts of the same currency
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	if (other.Amount > 0 && m.Amount > math.MaxInt64-other.Amount) || (other.Amount < 0 && m.Amount < math.MinInt64-other.Amount) {
+		return Money{}, ErrInvalidAmount
+	}
+	return Money{Amount: m.Amount + other.Amount, Currency: m.Currency}, nil
+}
+
+// Decimal returns the amount as a decimal string with the currency scale, e.g. "10.50"
+func (m Money) Decimal() string {
+	scale := currencyScales[m.Currency]
+	digits := strconv.FormatInt(m.Amount, 10)
+	sign := ""
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+	if scale == 0 {
+		return sign + digits
+	}
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+}
+
+// String returns the amount followed by the currency, e.g. "10.50 BRL"
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+// MarshalJSON writes the amount as a decimal string, JSON numbers would be read back as floats
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   string `json:"amount"`
+		Currency string `json:"currency"`
+	}{m.Decimal(), m.Currency})
+}
+
+// UnmarshalJSON accepts {"amount": "10.50", "currency": "BRL"}, with the amount as string or number.
+// A bare amount ("10.50" or 10.5) and a missing currency use DefaultCurrency.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*m = Money{}
+		return nil
+	}
+	var input struct {
+		Amount   json.RawMessage `json:"amount"`
+		Currency string          `json:"currency"`
+	}
+	if len(data) > 0 && data[0] == '{' {
+		if err := json.Unmarshal(data, &input); err != nil {
+			return err
+		}
+	} else {
+		input.Amount = data
+	}
+	if input.Currency == "" {
+		input.Currency = DefaultCurrency
+	}
+
+	amount, err := decimalLiteral(input.Amount)
+	if err != nil {
+		return err
+	}
+	parsed, err := ParseMoney(amount, input.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// decimalLiteral returns the text of a JSON string or number without converting it to float
+func decimalLiteral(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 {
+		return "", ErrInvalidAmount
+	}
+	if raw[0] == '"' {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return "", ErrInvalidAmount
+		}
+		return s, nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(raw, &n); err != nil {
+		return "", ErrInvalidAmount
+	}
+	// Exponents (1e3) are rejected by ParseMoney
+	return n.String(), nil
+}
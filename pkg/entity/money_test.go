@@ -0,0 +1,81 @@
+package entity
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMoney(t *testing.T) {
+	cases := []struct {
+		amount   string
+		currency string
+		minor    int64
+	}{
+		{"10.50", "BRL", 1050},
+		{"10.5", "brl", 1050},
+		{"10", "USD", 1000},
+		{"0.01", "EUR", 1},
+		{"-3.20", "GBP", -320},
+		{"1500", "JPY", 1500},
+	}
+	for _, c := range cases {
+		m, err := ParseMoney(c.amount, c.currency)
+		assert.NoError(t, err, c.amount)
+		assert.Equal(t, c.minor, m.Amount, c.amount)
+	}
+
+	_, err := ParseMoney("10.505", "BRL")
+	assert.ErrorIs(t, err, ErrInvalidScale)
+	_, err = ParseMoney("10.5", "JPY")
+	assert.ErrorIs(t, err, ErrInvalidScale)
+	_, err = ParseMoney("10.50", "XYZ")
+	assert.ErrorIs(t, err, ErrUnsupportedCurrency)
+	for _, invalid := range []string{"", "abc", "1,50", "1e3", ".5", "10.", "99999999999999999999"} {
+		_, err = ParseMoney(invalid, "BRL")
+		assert.ErrorIs(t, err, ErrInvalidAmount, invalid)
+	}
+}
+
+func TestMoneyDecimal(t *testing.T) {
+	assert.Equal(t, "10.50", MustParseMoney("10.5", "BRL").Decimal())
+	assert.Equal(t, "0.05", MustParseMoney("0.05", "BRL").Decimal())
+	assert.Equal(t, "-0.05", MustParseMoney("-0.05", "BRL").Decimal())
+	assert.Equal(t, "1500", MustParseMoney("1500", "JPY").Decimal())
+	assert.Equal(t, "10.50 BRL", MustParseMoney("10.5", "BRL").String())
+}
+
+func TestMoneyAdd(t *testing.T) {
+	total, err := MustParseMoney("0.10", "BRL").Add(MustParseMoney("0.20", "BRL"))
+	assert.NoError(t, err)
+	assert.Equal(t, "0.30", total.Decimal())
+
+	_, err = MustParseMoney("1", "BRL").Add(MustParseMoney("1", "USD"))
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+}
+
+func TestMoneyJSON(t *testing.T) {
+	data, err := json.Marshal(MustParseMoney("10.5", "USD"))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"amount":"10.50","currency":"USD"}`, string(data))
+
+	inputs := map[string]Money{
+		`{"amount":"10.50","currency":"USD"}`: {1050, "USD"},
+		`{"amount":10.5,"currency":"usd"}`:    {1050, "USD"},
+		`{"amount":"10.50"}`:                  {1050, DefaultCurrency},
+		`"10.50"`:                             {1050, DefaultCurrency},
+		`0.1`:                                 {10, DefaultCurrency},
+	}
+	for input, expected := range inputs {
+		var m Money
+		assert.NoError(t, json.Unmarshal([]byte(input), &m), input)
+		assert.Equal(t, expected, m, input)
+	}
+
+	var m Money
+	assert.ErrorIs(t, json.Unmarshal([]byte(`{"amount":"10.505","currency":"BRL"}`), &m), ErrInvalidScale)
+	assert.ErrorIs(t, json.Unmarshal([]byte(`{"amount":"10","currency":"ABC"}`), &m), ErrUnsupportedCurrency)
+	assert.ErrorIs(t, json.Unmarshal([]byte(`{"currency":"BRL"}`), &m), ErrInvalidAmount)
+	assert.ErrorIs(t, json.Unmarshal([]byte(`true`), &m), ErrInvalidAmount)
+}
@@ -0,0 +1,243 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Type is the kind of value a field accepts
+type Type int
+
+const (
+	String Type = iota
+	Number
+	Time
+	Bool
+)
+
+var allowedOperators = map[Type][]Operator{
+	String: {Eq, Ne, Contains, StartsWith, EndsWith, In},
+	Number: {Eq, Ne, Gt, Ge, Lt, Le, In},
+	Time:   {Eq, Ne, Gt, Ge, Lt, Le},
+	Bool:   {Eq, Ne},
+}
+
+// Operators of string fields limited to Values
+var enumOperators = []Operator{Eq, Ne, In}
+
+// Field is an entry of the whitelist
+type Field struct {
+	// Column used in the SQL, never taken from the filter text
+	Column string
+	Type   Type
+	// Values, when set, are the only accepted values of a String field, compared ignoring case
+	Values []string
+	// Condition builds the SQL itself for fields that are not a single column.
+	// It receives the values already checked against Type.
+	Condition func(op Operator, values []string) (string, []interface{}, error)
+}
+
+// Fields maps the names accepted in the filter to their definitions
+type Fields map[string]Field
+
+const dateLayout = "2006-01-02"
+
+var numberPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// Compile translates the AST into a SQL condition with ? placeholders and its arguments
+func Compile(node Node, fields Fields) (string, []interface{}, error) {
+	switch n := node.(type) {
+	case *And:
+		return compileBinary("AND", n.Left, n.Right, fields)
+	case *Or:
+		return compileBinary("OR", n.Left, n.Right, fields)
+	case *Not:
+		sql, args, err := Compile(n.Expr, fields)
+		if err != nil {
+			return "", nil, err
+		}
+		return "NOT (" + sql + ")", args, nil
+	case *Comparison:
+		return compileComparison(n, fields)
+	}
+	return "", nil, fmt.Errorf("filter: unknown node %T", node)
+}
+
+func compileBinary(keyword string, left, right Node, fields Fields) (string, []interface{}, error) {
+	leftSQL, leftArgs, err := Compile(left, fields)
+	if err != nil {
+		return "", nil, err
+	}
+	rightSQL, rightArgs, err := Compile(right, fields)
+	if err != nil {
+		return "", nil, err
+	}
+	return "(" + leftSQL + " " + keyword + " " + rightSQL + ")", append(leftArgs, rightArgs...), nil
+}
+
+func compileComparison(c *Comparison, fields Fields) (string, []interface{}, error) {
+	field, ok := fields[c.Field.Text]
+	if !ok {
+		return "", nil, errorAt(c.Field, "unknown field %q, use one of %s", c.Field.Text, fields.names())
+	}
+	allowed := allowedOperators[field.Type]
+	if len(field.Values) > 0 {
+		allowed = enumOperators
+	}
+	if !hasOperator(allowed, c.Op) {
+		return "", nil, &Error{
+			Message: fmt.Sprintf("operator %s is not supported by field %s", c.Op, c.Field.Text),
+			Pos:     c.OpPos,
+			Token:   string(c.Op),
+		}
+	}
+
+	values := make([]string, len(c.Values))
+	for i, v := range c.Values {
+		value, err := field.check(v)
+		if err != nil {
+			return "", nil, err
+		}
+		values[i] = value
+	}
+	if field.Condition != nil {
+		sql, args, err := field.Condition(c.Op, values)
+		if err != nil {
+			return "", nil, errorAt(c.Values[0], "%s", err.Error())
+		}
+		return sql, args, nil
+	}
+
+	column := field.Column
+	switch c.Op {
+	case In:
+		args := make([]interface{}, len(values))
+		for i, v := range values {
+			args[i] = field.arg(v)
+		}
+		return column + " IN ?", []interface{}{args}, nil
+	case Contains:
+		return "LOWER(" + column + ") LIKE ? ESCAPE '!'", []interface{}{"%" + escapeLike(values[0]) + "%"}, nil
+	case StartsWith:
+		return "LOWER(" + column + ") LIKE ? ESCAPE '!'", []interface{}{escapeLike(values[0]) + "%"}, nil
+	case EndsWith:
+		return "LOWER(" + column + ") LIKE ? ESCAPE '!'", []interface{}{"%" + escapeLike(values[0])}, nil
+	}
+	if field.Type == Time && len(values[0]) == len(dateLayout) {
+		return dateCondition(column, c.Op, values[0])
+	}
+	return column + " " + c.Op.SQL() + " ?", []interface{}{field.arg(values[0])}, nil
+}
+
+var sqlOperators = map[Operator]string{Eq: "=", Ne: "<>", Gt: ">", Ge: ">=", Lt: "<", Le: "<="}
+
+// SQL returns the SQL comparison of eq, ne, gt, ge, lt and le, empty for the other operators
+func (op Operator) SQL() string {
+	return sqlOperators[op]
+}
+
+// check validates the value against the field and returns it normalized
+func (f Field) check(v Token) (string, error) {
+	switch f.Type {
+	case Number:
+		if !numberPattern.MatchString(v.Text) {
+			return "", errorAt(v, "expected a number")
+		}
+	case Time:
+		if _, err := parseTime(v.Text); err != nil {
+			return "", errorAt(v, "expected a date (2006-01-02) or date-time (RFC 3339)")
+		}
+	case Bool:
+		if _, err := strconv.ParseBool(v.Text); err != nil {
+			return "", errorAt(v, "expected true or false")
+		}
+	case String:
+		if len(f.Values) > 0 {
+			for _, allowed := range f.Values {
+				if strings.EqualFold(allowed, v.Text) {
+					return allowed, nil
+				}
+			}
+			return "", errorAt(v, "expected one of %s", strings.Join(f.Values, ", "))
+		}
+	}
+	return v.Text, nil
+}
+
+// arg converts a value already checked into the SQL argument
+func (f Field) arg(value string) interface{} {
+	switch f.Type {
+	case Number:
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i
+		}
+		n, _ := strconv.ParseFloat(value, 64)
+		return n
+	case Time:
+		t, _ := parseTime(value)
+		return t.Local()
+	case Bool:
+		b, _ := strconv.ParseBool(value)
+		return b
+	}
+	return value
+}
+
+// dateCondition treats a date without time as the whole day (UTC), so
+// created_at eq 2026-01-01 matches anything on that day
+func dateCondition(column string, op Operator, value string) (string, []interface{}, error) {
+	start, _ := time.Parse(dateLayout, value)
+	end := start.AddDate(0, 0, 1)
+	// Same instant in the local zone, the sqlite driver compares times as text in the zone they were written
+	start, end = start.Local(), end.Local()
+	switch op {
+	case Eq:
+		return "(" + column + " >= ? AND " + column + " < ?)", []interface{}{start, end}, nil
+	case Ne:
+		return "(" + column + " < ? OR " + column + " >= ?)", []interface{}{start, end}, nil
+	case Gt:
+		return column + " >= ?", []interface{}{end}, nil
+	case Ge:
+		return column + " >= ?", []interface{}{start}, nil
+	case Lt:
+		return column + " < ?", []interface{}{start}, nil
+	default:
+		return column + " < ?", []interface{}{end}, nil
+	}
+}
+
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(dateLayout, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339Nano, value)
+}
+
+// escapeLike lowers the text and escapes the LIKE wildcards with !, which
+// needs no extra escaping in any of the supported databases
+func escapeLike(value string) string {
+	value = strings.ToLower(value)
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(value)
+}
+
+func hasOperator(operators []Operator, op Operator) bool {
+	for _, o := range operators {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (f Fields) names() string {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
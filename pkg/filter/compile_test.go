@@ -0,0 +1,94 @@
+package filter
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testFields = Fields{
+	"name":       {Column: "name", Type: String},
+	"status":     {Column: "status", Type: String, Values: []string{"draft", "active"}},
+	"stock":      {Column: "stock", Type: Number},
+	"created_at": {Column: "created_at", Type: Time},
+	"available":  {Column: "available", Type: Bool},
+	"price": {Type: Number, Condition: func(op Operator, values []string) (string, []interface{}, error) {
+		if values[0] == "0" {
+			return "", nil, errors.New("price must not be zero")
+		}
+		return "price_amount " + op.SQL() + " ?", []interface{}{values[0] + "00"}, nil
+	}},
+}
+
+func compile(t *testing.T, input string) (string, []interface{}, error) {
+	node, err := Parse(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Compile(node, testFields)
+}
+
+func TestCompile(t *testing.T) {
+	sql, args, err := compile(t, "stock gt 10 and (name contains '50%_off' or not status in (DRAFT, active))")
+	assert.NoError(t, err)
+	assert.Equal(t, "(stock > ? AND (LOWER(name) LIKE ? ESCAPE '!' OR NOT (status IN ?)))", sql)
+	assert.Equal(t, []interface{}{int64(10), "%50!%!_off%", []interface{}{"draft", "active"}}, args)
+
+	sql, args, err = compile(t, "stock le 1.5 and available eq true and name startswith 'Ch'")
+	assert.NoError(t, err)
+	assert.Equal(t, "((stock <= ? AND available = ?) AND LOWER(name) LIKE ? ESCAPE '!')", sql)
+	assert.Equal(t, []interface{}{1.5, true, "ch%"}, args)
+
+	sql, args, err = compile(t, "price gt 10")
+	assert.NoError(t, err)
+	assert.Equal(t, "price_amount > ?", sql)
+	assert.Equal(t, []interface{}{"1000"}, args)
+}
+
+func TestCompileDates(t *testing.T) {
+	day := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	next := day.AddDate(0, 0, 1)
+
+	sql, args, err := compile(t, "created_at eq 2026-01-01")
+	assert.NoError(t, err)
+	assert.Equal(t, "(created_at >= ? AND created_at < ?)", sql)
+	assert.True(t, day.Equal(args[0].(time.Time)))
+	assert.True(t, next.Equal(args[1].(time.Time)))
+
+	sql, args, err = compile(t, "created_at gt 2026-01-01")
+	assert.NoError(t, err)
+	assert.Equal(t, "created_at >= ?", sql)
+	assert.True(t, next.Equal(args[0].(time.Time)))
+
+	sql, args, err = compile(t, "created_at lt '2026-01-01T10:00:00-03:00'")
+	assert.NoError(t, err)
+	assert.Equal(t, "created_at < ?", sql)
+	assert.True(t, time.Date(2026, 1, 1, 13, 0, 0, 0, time.UTC).Equal(args[0].(time.Time)))
+}
+
+func TestCompileErrorsPointAtToken(t *testing.T) {
+	cases := []struct {
+		input string
+		pos   int
+		token string
+	}{
+		{"password eq 'x'", 1, "password"},
+		{"stock contains 1", 7, "contains"},
+		{"status gt draft", 8, "gt"},
+		{"stock gt ten", 10, "ten"},
+		{"status eq deleted", 11, "deleted"},
+		{"created_at ge yesterday", 15, "yesterday"},
+		{"available eq maybe", 14, "maybe"},
+		{"stock gt 1 and price eq 0", 25, "0"},
+	}
+	for _, c := range cases {
+		_, _, err := compile(t, c.input)
+		var filterErr *Error
+		if assert.ErrorAs(t, err, &filterErr, c.input) {
+			assert.Equal(t, c.pos, filterErr.Pos, c.input)
+			assert.Equal(t, c.token, filterErr.Token, c.input)
+		}
+	}
+}
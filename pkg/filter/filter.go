@@ -0,0 +1,100 @@
+// Package filter implements a small expression language for filtering
+// listings from the query string, e.g.
+//
+//	price gt 10 and name contains 'chair' and created_at ge 2026-01-01
+//
+// Parse turns the text into an AST and Compile translates the AST into a
+// parameterized SQL condition, accepting only the fields of a whitelist.
+// Every error is an *Error with the position of the offending token.
+package filter
+
+import "fmt"
+
+// Limits that keep a single filter cheap to parse and to run
+const (
+	MaxLength      = 1000
+	MaxConditions  = 20
+	MaxNestedDepth = 10
+)
+
+// Operator compares a field with one or more values
+type Operator string
+
+const (
+	Eq         Operator = "eq"
+	Ne         Operator = "ne"
+	Gt         Operator = "gt"
+	Ge         Operator = "ge"
+	Lt         Operator = "lt"
+	Le         Operator = "le"
+	Contains   Operator = "contains"
+	StartsWith Operator = "startswith"
+	EndsWith   Operator = "endswith"
+	In         Operator = "in"
+)
+
+var operators = map[string]Operator{
+	"eq": Eq, "ne": Ne, "gt": Gt, "ge": Ge, "lt": Lt, "le": Le,
+	"contains": Contains, "startswith": StartsWith, "endswith": EndsWith, "in": In,
+}
+
+// Token is a piece of the filter text, Pos is the 1-based character where it starts
+type Token struct {
+	Text string
+	Pos  int
+	// Quoted is true for 'string' literals, Text holds the unquoted value
+	Quoted bool
+}
+
+// Node is an element of the AST: *And, *Or, *Not or *Comparison
+type Node interface {
+	node()
+}
+
+// And matches when both sides match
+type And struct {
+	Left, Right Node
+}
+
+// Or matches when at least one side matches
+type Or struct {
+	Left, Right Node
+}
+
+// Not matches when Expr does not
+type Not struct {
+	Expr Node
+}
+
+// Comparison is a single condition, e.g. price gt 10 or status in (draft, active)
+type Comparison struct {
+	Field  Token
+	Op     Operator
+	OpPos  int
+	Values []Token
+}
+
+func (*And) node()        {}
+func (*Or) node()         {}
+func (*Not) node()        {}
+func (*Comparison) node() {}
+
+// Error describes an invalid filter and where the problem is
+type Error struct {
+	Message string
+	// 1-based character position of the offending token, len+1 at the end of the text
+	Pos int
+	// Offending token, empty at the end of the text
+	Token string
+}
+
+func (e *Error) Error() string {
+	if e.Token == "" {
+		return fmt.Sprintf("%s at end of filter", e.Message)
+	}
+	return fmt.Sprintf("%s at position %d near %q", e.Message, e.Pos, e.Token)
+}
+
+func errorAt(t Token, format string, args ...interface{}) *Error {
+	return &Error{Message: fmt.Sprintf(format, args...), Pos: t.Pos, Token: t.Text}
+}
@@ -0,0 +1,250 @@
+package filter
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var identifierPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+type tokenKind int
+
+const (
+	kindEOF tokenKind = iota
+	kindWord
+	kindString
+	kindLParen
+	kindRParen
+	kindComma
+)
+
+type lexeme struct {
+	Token
+	kind tokenKind
+}
+
+// lex splits the text into words, 'strings', parentheses and commas.
+// A quote inside a string is escaped by writing it twice.
+func lex(input string) ([]lexeme, error) {
+	var lexemes []lexeme
+	runes := []rune(input)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		pos := i + 1
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')' || r == ',':
+			kind := map[rune]tokenKind{'(': kindLParen, ')': kindRParen, ',': kindComma}[r]
+			lexemes = append(lexemes, lexeme{Token{Text: string(r), Pos: pos}, kind})
+			i++
+		case r == '\'':
+			var value strings.Builder
+			i++
+			for {
+				if i >= len(runes) {
+					return nil, &Error{Message: "unterminated string", Pos: pos, Token: string(runes[pos-1:])}
+				}
+				if runes[i] == '\'' {
+					if i+1 < len(runes) && runes[i+1] == '\'' {
+						value.WriteRune('\'')
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				value.WriteRune(runes[i])
+				i++
+			}
+			lexemes = append(lexemes, lexeme{Token{Text: value.String(), Pos: pos, Quoted: true}, kindString})
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune("(),'", runes[i]) {
+				i++
+			}
+			lexemes = append(lexemes, lexeme{Token{Text: string(runes[start:i]), Pos: pos}, kindWord})
+		}
+	}
+	lexemes = append(lexemes, lexeme{Token{Pos: len(runes) + 1}, kindEOF})
+	return lexemes, nil
+}
+
+type parser struct {
+	lexemes     []lexeme
+	current     int
+	depth       int
+	comparisons int
+}
+
+// Parse reads a filter expression. The grammar, with keywords in any case:
+//
+//	expr       = term { "or" term }
+//	term       = factor { "and" factor }
+//	factor     = "not" factor | "(" expr ")" | comparison
+//	comparison = field operator value | field "in" "(" value { "," value } ")"
+//	value      = 'quoted string' | word
+func Parse(input string) (Node, error) {
+	if utf8.RuneCountInString(input) > MaxLength {
+		return nil, &Error{Message: "filter is too long", Pos: MaxLength + 1, Token: string([]rune(input)[MaxLength:])}
+	}
+	lexemes, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{lexemes: lexemes}
+	if p.peek().kind == kindEOF {
+		return nil, errorAt(p.peek().Token, "empty filter")
+	}
+	node, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if next := p.peek(); next.kind != kindEOF {
+		return nil, errorAt(next.Token, "expected and, or or the end of the filter")
+	}
+	return node, nil
+}
+
+func (p *parser) peek() lexeme {
+	return p.lexemes[p.current]
+}
+
+func (p *parser) next() lexeme {
+	l := p.lexemes[p.current]
+	if l.kind != kindEOF {
+		p.current++
+	}
+	return l
+}
+
+// keyword checks whether the next token is the unquoted word, ignoring case
+func (p *parser) keyword(word string) bool {
+	l := p.peek()
+	return l.kind == kindWord && strings.EqualFold(l.Text, word)
+}
+
+func (p *parser) expr() (Node, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		p.next()
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		left = &Or{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) term() (Node, error) {
+	left, err := p.factor()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		p.next()
+		right, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		left = &And{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) factor() (Node, error) {
+	if p.keyword("not") || p.peek().kind == kindLParen {
+		p.depth++
+		defer func() { p.depth-- }()
+		if p.depth > MaxNestedDepth {
+			return nil, errorAt(p.peek().Token, "filter is nested too deeply")
+		}
+	}
+	if p.keyword("not") {
+		p.next()
+		expr, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Expr: expr}, nil
+	}
+	if p.peek().kind == kindLParen {
+		p.next()
+		expr, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != kindRParen {
+			return nil, errorAt(closing.Token, "expected )")
+		}
+		return expr, nil
+	}
+	return p.comparison()
+}
+
+func (p *parser) comparison() (Node, error) {
+	field := p.next()
+	if field.kind != kindWord || !identifierPattern.MatchString(strings.ToLower(field.Text)) || isKeyword(field.Text) {
+		return nil, errorAt(field.Token, "expected a field name")
+	}
+	p.comparisons++
+	if p.comparisons > MaxConditions {
+		return nil, errorAt(field.Token, "filter has more than %d conditions", MaxConditions)
+	}
+	field.Text = strings.ToLower(field.Text)
+
+	opToken := p.next()
+	op, ok := operators[strings.ToLower(opToken.Text)]
+	if opToken.kind != kindWord || !ok {
+		return nil, errorAt(opToken.Token, "expected an operator (eq, ne, gt, ge, lt, le, contains, startswith, endswith or in)")
+	}
+	comparison := &Comparison{Field: field.Token, Op: op, OpPos: opToken.Pos}
+
+	if op != In {
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		comparison.Values = []Token{value}
+		return comparison, nil
+	}
+	if open := p.next(); open.kind != kindLParen {
+		return nil, errorAt(open.Token, "expected ( after in")
+	}
+	for {
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		comparison.Values = append(comparison.Values, value)
+		separator := p.next()
+		if separator.kind == kindRParen {
+			return comparison, nil
+		}
+		if separator.kind != kindComma {
+			return nil, errorAt(separator.Token, "expected , or )")
+		}
+	}
+}
+
+func (p *parser) value() (Token, error) {
+	value := p.next()
+	if value.kind == kindString || (value.kind == kindWord && !isKeyword(value.Text)) {
+		return value.Token, nil
+	}
+	return Token{}, errorAt(value.Token, "expected a value")
+}
+
+func isKeyword(word string) bool {
+	switch strings.ToLower(word) {
+	case "and", "or", "not":
+		return true
+	}
+	return false
+}
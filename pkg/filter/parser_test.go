@@ -0,0 +1,83 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePrecedence(t *testing.T) {
+	node, err := Parse("price gt 10 and name contains 'chair' or not (stock EQ 0)")
+	assert.NoError(t, err)
+
+	or, ok := node.(*Or)
+	assert.True(t, ok)
+	and, ok := or.Left.(*And)
+	assert.True(t, ok)
+	price := and.Left.(*Comparison)
+	assert.Equal(t, "price", price.Field.Text)
+	assert.Equal(t, Gt, price.Op)
+	assert.Equal(t, []Token{{Text: "10", Pos: 10}}, price.Values)
+	name := and.Right.(*Comparison)
+	assert.Equal(t, Contains, name.Op)
+	assert.Equal(t, []Token{{Text: "chair", Pos: 31, Quoted: true}}, name.Values)
+	not, ok := or.Right.(*Not)
+	assert.True(t, ok)
+	assert.Equal(t, Eq, not.Expr.(*Comparison).Op)
+}
+
+func TestParseStringsAndLists(t *testing.T) {
+	node, err := Parse("name eq 'it''s a chair' AND status in (draft, 'active')")
+	assert.NoError(t, err)
+	and := node.(*And)
+	assert.Equal(t, "it's a chair", and.Left.(*Comparison).Values[0].Text)
+	status := and.Right.(*Comparison)
+	assert.Equal(t, In, status.Op)
+	assert.Len(t, status.Values, 2)
+	assert.Equal(t, "active", status.Values[1].Text)
+}
+
+func TestParseErrorsPointAtToken(t *testing.T) {
+	cases := []struct {
+		input string
+		pos   int
+		token string
+	}{
+		{"", 1, ""},
+		{"price", 6, ""},
+		{"price greater 10", 7, "greater"},
+		{"price gt", 9, ""},
+		{"price gt 10 name eq 'x'", 13, "name"},
+		{"(price gt 10", 13, ""},
+		{"price gt 10 and", 16, ""},
+		{"name eq 'chair", 9, "'chair"},
+		{"10 gt price", 1, "10"},
+		{"status in (draft active)", 18, "active"},
+		{"status in draft", 11, "draft"},
+		{"name eq and", 9, "and"},
+	}
+	for _, c := range cases {
+		_, err := Parse(c.input)
+		var filterErr *Error
+		if assert.ErrorAs(t, err, &filterErr, c.input) {
+			assert.Equal(t, c.pos, filterErr.Pos, c.input)
+			assert.Equal(t, c.token, filterErr.Token, c.input)
+		}
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	_, err := Parse(strings.Repeat("a", MaxLength+1))
+	assert.ErrorContains(t, err, "too long")
+
+	conditions := make([]string, MaxConditions+1)
+	for i := range conditions {
+		conditions[i] = "stock gt 1"
+	}
+	_, err = Parse(strings.Join(conditions, " and "))
+	assert.ErrorContains(t, err, "more than")
+
+	_, err = Parse(strings.Repeat("not ", MaxNestedDepth+1) + "stock gt 1")
+	assert.ErrorContains(t, err, "nested too deeply")
+}
@@ -0,0 +1,56 @@
+// Package mergepatch implements JSON Merge Patch (RFC 7396).
+package mergepatch
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// ContentType is the media type of a merge patch document
+const ContentType = "application/merge-patch+json"
+
+// Apply merges patch into original and returns the resulting document.
+// Members set to null in the patch are removed, objects are merged
+// recursively and any other value replaces the original one.
+func Apply(original, patch []byte) ([]byte, error) {
+	target, err := decode(original)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := decode(patch)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(merge(target, changes))
+}
+
+// decode keeps numbers as json.Number so values the patch does not touch
+// are written back exactly as they were read
+func decode(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func merge(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+			continue
+		}
+		targetObject[name] = merge(targetObject[name], value)
+	}
+	return targetObject
+}
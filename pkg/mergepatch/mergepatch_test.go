@@ -0,0 +1,46 @@
+package mergepatch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Exemplos do apêndice A da RFC 7396
+func TestApplyRFCExamples(t *testing.T) {
+	cases := []struct {
+		original, patch, result string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, c := range cases {
+		result, err := Apply([]byte(c.original), []byte(c.patch))
+		assert.NoError(t, err, c.patch)
+		assert.JSONEq(t, c.result, string(result), c.patch)
+	}
+}
+
+func TestApplyKeepsNumbers(t *testing.T) {
+	result, err := Apply([]byte(`{"price":12345678901234567890.5,"name":"a"}`), []byte(`{"name":"b"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"b","price":12345678901234567890.5}`, string(result))
+}
+
+func TestApplyInvalidJSON(t *testing.T) {
+	_, err := Apply([]byte(`{}`), []byte(`{"a":`))
+	assert.Error(t, err)
+}